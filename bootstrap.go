@@ -0,0 +1,269 @@
+package reductions
+
+import (
+	"errors"
+	"fmt"
+	"gonum.org/v1/gonum/stat"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// SetSplitter separates distance records into the close and far sequence sets
+type SetSplitter func([]DistanceRecord) ([]DistanceRecord, []DistanceRecord)
+
+// RadiusSplitter returns a SetSplitter that calls MakeSequenceSets with the given radius
+func RadiusSplitter(radius float64) SetSplitter {
+	return func(distances []DistanceRecord) ([]DistanceRecord, []DistanceRecord) {
+		return MakeSequenceSets(distances, radius)
+	}
+}
+
+// BootstrapScheme sets what is resampled when bootstrapping distance records
+type BootstrapScheme int
+
+const (
+	// ResamplePairs draws distance records with replacement
+	ResamplePairs BootstrapScheme = iota
+	// ResampleSequences draws sequences with replacement and keeps all the pairs
+	// between drawn sequences, which respects the dependence between pairs sharing
+	// a sequence
+	ResampleSequences
+)
+
+// Interval is a confidence interval around an estimate
+type Interval struct {
+	Estimate, Lower, Upper float64
+}
+
+// String implements the Stringer interface for Interval structs
+func (interval Interval) String() string {
+	return fmt.Sprintf("%.4e [%.4e, %.4e]", interval.Estimate, interval.Lower, interval.Upper)
+}
+
+// PhiInterval holds bootstrap confidence intervals for all the terms of a PhiRecord.
+// Resamples is the number of replicates for which phi could be computed.
+type PhiInterval struct {
+	Phi, C, F, Mu Interval
+	Level         float64
+	Resamples     int
+}
+
+// PhiComparison is the result of a paired bootstrap comparison of 2 reductions.
+// Difference is the interval for PhiA - PhiB and PValue the two-sided bootstrap
+// p-value for the null hypothesis that both reductions have the same phi.
+type PhiComparison struct {
+	PhiA, PhiB PhiRecord
+	Difference Interval
+	PValue     float64
+	Resamples  int
+}
+
+// resampler draws bootstrap replicates of a slice of distance records
+type resampler struct {
+	scheme   BootstrapScheme
+	nRecords int
+	nKeys    int
+	pairKeys [][2]int
+}
+
+func newResampler(distances []DistanceRecord, scheme BootstrapScheme) *resampler {
+	r := &resampler{scheme: scheme, nRecords: len(distances)}
+	if scheme != ResampleSequences {
+		return r
+	}
+	keyIndex := map[string]int{}
+	r.pairKeys = make([][2]int, len(distances))
+	for i, record := range distances {
+		for j, key := range []string{record.Key1, record.Key2} {
+			if _, ok := keyIndex[key]; !ok {
+				keyIndex[key] = len(keyIndex)
+			}
+			r.pairKeys[i][j] = keyIndex[key]
+		}
+	}
+	r.nKeys = len(keyIndex)
+	return r
+}
+
+// draw returns the indices of the records in a bootstrap replicate
+func (r *resampler) draw(rng *rand.Rand) []int {
+	if r.scheme != ResampleSequences {
+		indices := make([]int, r.nRecords)
+		for i := range indices {
+			indices[i] = rng.Intn(r.nRecords)
+		}
+		return indices
+	}
+
+	counts := make([]int, r.nKeys)
+	for i := 0; i < r.nKeys; i++ {
+		counts[rng.Intn(r.nKeys)]++
+	}
+	indices := make([]int, 0, r.nRecords)
+	for i, keys := range r.pairKeys {
+		for w := 0; w < counts[keys[0]]*counts[keys[1]]; w++ {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
+
+// phiOfReplicate computes phi on the records at the given indices
+func phiOfReplicate(distances []DistanceRecord, indices []int, split SetSplitter) (PhiRecord, error) {
+	replicate := make([]DistanceRecord, len(indices))
+	for i, index := range indices {
+		replicate[i] = distances[index]
+	}
+	closeSet, farSet := split(replicate)
+	record, _, err := ObjectivePhiChecked(closeSet, farSet)
+	return record, err
+}
+
+// percentileInterval returns the percentile confidence interval of a set of replicates
+func percentileInterval(estimate float64, replicates []float64, level float64) Interval {
+	sort.Float64s(replicates)
+	alpha := (1 - level) / 2
+	return Interval{
+		Estimate: estimate,
+		Lower:    stat.Quantile(alpha, stat.Empirical, replicates, nil),
+		Upper:    stat.Quantile(1-alpha, stat.Empirical, replicates, nil),
+	}
+}
+
+func checkBootstrapParameters(distances []DistanceRecord, resamples int, level float64) error {
+	if len(distances) == 0 {
+		return errors.New("cannot bootstrap an empty set of distances")
+	}
+	if resamples < 1 {
+		return errors.New("the number of resamples must be positive")
+	}
+	if level <= 0 || level >= 1 {
+		return errors.New("the confidence level must be between 0 and 1")
+	}
+	return nil
+}
+
+// BootstrapPhi computes confidence intervals at the given level (e.g. 0.95) for
+// phi and its terms, from resamples bootstrap replicates of the distances.
+// Replicates for which phi is undefined (see ObjectivePhiChecked) are discarded.
+func BootstrapPhi(distances []DistanceRecord, split SetSplitter, scheme BootstrapScheme, resamples int, level float64, rng *rand.Rand) (PhiInterval, error) {
+	if err := checkBootstrapParameters(distances, resamples, level); err != nil {
+		return PhiInterval{}, err
+	}
+
+	closeSet, farSet := split(distances)
+	estimate, _, err := ObjectivePhiChecked(closeSet, farSet)
+	if err != nil {
+		return PhiInterval{}, err
+	}
+	r := newResampler(distances, scheme)
+	phis, cs, fs, mus := []float64{}, []float64{}, []float64{}, []float64{}
+	for i := 0; i < resamples; i++ {
+		record, err := phiOfReplicate(distances, r.draw(rng), split)
+		if err != nil {
+			continue
+		}
+		phis = append(phis, record.Phi)
+		cs = append(cs, record.C)
+		fs = append(fs, record.F)
+		mus = append(mus, record.Mu)
+	}
+	if len(phis) == 0 {
+		return PhiInterval{}, errors.New("phi is undefined for all bootstrap replicates")
+	}
+
+	return PhiInterval{
+		Phi:       percentileInterval(estimate.Phi, phis, level),
+		C:         percentileInterval(estimate.C, cs, level),
+		F:         percentileInterval(estimate.F, fs, level),
+		Mu:        percentileInterval(estimate.Mu, mus, level),
+		Level:     level,
+		Resamples: len(phis),
+	}, nil
+}
+
+// alignRecords orders the records of b so they are on the same pair of sequences
+// as the records of a
+func alignRecords(a, b []DistanceRecord) ([]DistanceRecord, error) {
+	if len(a) != len(b) {
+		return nil, fmt.Errorf("the distance sets have different sizes (%d and %d)", len(a), len(b))
+	}
+	byKeys := make(map[[2]string]DistanceRecord, len(b))
+	for _, record := range b {
+		sorted := sortRecordKeys(record)
+		byKeys[[2]string{sorted.Key1, sorted.Key2}] = record
+	}
+	aligned := make([]DistanceRecord, len(a))
+	for i, record := range a {
+		sorted := sortRecordKeys(record)
+		other, ok := byKeys[[2]string{sorted.Key1, sorted.Key2}]
+		if !ok {
+			return nil, fmt.Errorf("pair %s, %s is missing from the second distance set", record.Key1, record.Key2)
+		}
+		aligned[i] = other
+	}
+	return aligned, nil
+}
+
+// ComparePhi compares 2 reductions evaluated on the same pairs of sequences with a
+// paired bootstrap: both reductions are evaluated on the same replicates and the
+// distribution of the differences in phi gives a confidence interval and a p-value.
+func ComparePhi(distancesA, distancesB []DistanceRecord, split SetSplitter, scheme BootstrapScheme, resamples int, level float64, rng *rand.Rand) (PhiComparison, error) {
+	if err := checkBootstrapParameters(distancesA, resamples, level); err != nil {
+		return PhiComparison{}, err
+	}
+	alignedB, err := alignRecords(distancesA, distancesB)
+	if err != nil {
+		return PhiComparison{}, err
+	}
+
+	closeA, farA := split(distancesA)
+	phiA, _, err := ObjectivePhiChecked(closeA, farA)
+	if err != nil {
+		return PhiComparison{}, err
+	}
+	closeB, farB := split(alignedB)
+	phiB, _, err := ObjectivePhiChecked(closeB, farB)
+	if err != nil {
+		return PhiComparison{}, err
+	}
+	r := newResampler(distancesA, scheme)
+	differences := []float64{}
+	var below, above int
+	for i := 0; i < resamples; i++ {
+		indices := r.draw(rng)
+		replicateA, errA := phiOfReplicate(distancesA, indices, split)
+		replicateB, errB := phiOfReplicate(alignedB, indices, split)
+		if errA != nil || errB != nil {
+			continue
+		}
+		difference := replicateA.Phi - replicateB.Phi
+		differences = append(differences, difference)
+		if difference <= 0 {
+			below++
+		}
+		if difference >= 0 {
+			above++
+		}
+	}
+	if len(differences) == 0 {
+		return PhiComparison{}, errors.New("phi is undefined for all bootstrap replicates")
+	}
+
+	pValue := 2 * float64(minInt(below, above)) / float64(len(differences))
+	return PhiComparison{
+		PhiA:       phiA,
+		PhiB:       phiB,
+		Difference: percentileInterval(phiA.Phi-phiB.Phi, differences, level),
+		PValue:     math.Min(pValue, 1),
+		Resamples:  len(differences),
+	}, nil
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,115 @@
+package reductions
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// makeBootstrapDistances builds distances between nSeqs sequences where pairs of
+// sequences with close indices are close, with a reduced distance scaled by factor
+func makeBootstrapDistances(rng *rand.Rand, nSeqs int, factor float64) []DistanceRecord {
+	distances := []DistanceRecord{}
+	for i := 0; i < nSeqs; i++ {
+		for j := i + 1; j < nSeqs; j++ {
+			raw := 0.1 * float64(j-i) * (1 + 0.1*rng.Float64())
+			distances = append(distances, DistanceRecord{
+				Key1:            fmt.Sprintf("seq%d", i),
+				Key2:            fmt.Sprintf("seq%d", j),
+				RawDistance:     raw,
+				ReducedDistance: raw * factor * (1 + 0.2*rng.Float64()),
+			})
+		}
+	}
+	return distances
+}
+
+func TestBootstrapPhi(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	distances := makeBootstrapDistances(rng, 15, 1)
+	split := RadiusSplitter(0.35)
+	estimate, _, _ := ObjectivePhiChecked(split(distances))
+
+	for _, scheme := range []BootstrapScheme{ResamplePairs, ResampleSequences} {
+		t.Run(fmt.Sprintf("scheme%d", scheme), func(t *testing.T) {
+			interval, err := BootstrapPhi(distances, split, scheme, 200, 0.95, rng)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if interval.Phi.Estimate != estimate.Phi || interval.Mu.Estimate != estimate.Mu {
+				t.Errorf("estimates should be the phi of the full set: %v, %v", interval, estimate)
+			}
+			for _, term := range []Interval{interval.Phi, interval.C, interval.F, interval.Mu} {
+				if term.Lower > term.Upper || term.Lower > term.Estimate || term.Upper < term.Estimate {
+					t.Errorf("inconsistent interval %v", term)
+				}
+			}
+			if interval.Resamples == 0 || interval.Resamples > 200 {
+				t.Errorf("unexpected number of valid resamples: %d", interval.Resamples)
+			}
+		})
+	}
+}
+
+func TestBootstrapPhiErrors(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	distances := makeBootstrapDistances(rng, 5, 1)
+	split := RadiusSplitter(0.35)
+	tests := []struct {
+		name      string
+		distances []DistanceRecord
+		resamples int
+		level     float64
+	}{
+		{name: "Empty", distances: []DistanceRecord{}, resamples: 10, level: 0.95},
+		{name: "NoResamples", distances: distances, resamples: 0, level: 0.95},
+		{name: "BadLevel", distances: distances, resamples: 10, level: 1.5},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := BootstrapPhi(testCase.distances, split, ResamplePairs, testCase.resamples, testCase.level, rng)
+			if err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
+
+func TestComparePhi(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	distancesA := makeBootstrapDistances(rng, 15, 1)
+	split := RadiusSplitter(0.35)
+
+	// B has the same raw distances but twice the reduced distances in the close set
+	distancesB := make([]DistanceRecord, len(distancesA))
+	for i, record := range distancesA {
+		record.Key1, record.Key2 = record.Key2, record.Key1
+		if record.RawDistance <= 0.35 {
+			record.ReducedDistance *= 2
+		}
+		distancesB[len(distancesA)-1-i] = record
+	}
+
+	comparison, err := ComparePhi(distancesA, distancesB, split, ResampleSequences, 200, 0.95, rng)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if comparison.Difference.Estimate >= 0 || comparison.Difference.Upper >= 0 {
+		t.Errorf("reduction A should be significantly better: %v", comparison.Difference)
+	}
+	if comparison.PValue > 0.05 {
+		t.Errorf("expected a small p-value, got %v", comparison.PValue)
+	}
+
+	same, err := ComparePhi(distancesA, distancesA, split, ResamplePairs, 200, 0.95, rng)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if same.PValue != 1 || same.Difference.Estimate != 0 {
+		t.Errorf("identical reductions should not differ: %v", same)
+	}
+
+	if _, err := ComparePhi(distancesA, distancesB[1:], split, ResamplePairs, 10, 0.95, rng); err == nil {
+		t.Errorf("expected an error for distance sets of different sizes")
+	}
+}
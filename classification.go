@@ -0,0 +1,178 @@
+package reductions
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// ROCPoint is a point of a ROC curve: pairs with a reduced distance <= Threshold
+// are predicted to be close
+type ROCPoint struct {
+	Threshold, FPR, TPR float64
+}
+
+// PRPoint is a point of a precision-recall curve: pairs with a reduced
+// distance <= Threshold are predicted to be close
+type PRPoint struct {
+	Threshold, Precision, Recall float64
+}
+
+// labeledDistance is a reduced distance labeled as close (positive) or far
+type labeledDistance struct {
+	distance float64
+	close    bool
+}
+
+// thresholdCounts holds the number of close and far pairs with a reduced
+// distance <= threshold
+type thresholdCounts struct {
+	threshold                     float64
+	truePositives, falsePositives int
+}
+
+// sweepThresholds returns the counts of true and false positives for every
+// distinct reduced distance used as a threshold, in increasing order. Failed
+// records (see FilterFailedRecords) are left out.
+func sweepThresholds(closeSet, farSet []DistanceRecord) ([]thresholdCounts, error) {
+	labeled := make([]labeledDistance, 0, len(closeSet)+len(farSet))
+	var nClose, nFar int
+	for _, record := range closeSet {
+		if !isFailedRecord(record) {
+			labeled = append(labeled, labeledDistance{distance: record.ReducedDistance, close: true})
+			nClose++
+		}
+	}
+	for _, record := range farSet {
+		if !isFailedRecord(record) {
+			labeled = append(labeled, labeledDistance{distance: record.ReducedDistance})
+			nFar++
+		}
+	}
+	if nClose == 0 || nFar == 0 {
+		return nil, errors.New("the close and far sets must not be empty")
+	}
+	sort.Slice(labeled, func(i, j int) bool { return labeled[i].distance < labeled[j].distance })
+
+	counts := []thresholdCounts{}
+	var tp, fp int
+	for i, point := range labeled {
+		if point.close {
+			tp++
+		} else {
+			fp++
+		}
+		if i+1 < len(labeled) && labeled[i+1].distance == point.distance {
+			continue
+		}
+		counts = append(counts, thresholdCounts{threshold: point.distance, truePositives: tp, falsePositives: fp})
+	}
+	return counts, nil
+}
+
+// ROCCurve computes the ROC curve of the reduced distances used to discriminate
+// close pairs from far pairs. The first point is (0, 0) at a threshold of -Inf.
+// Failed records are left out.
+func ROCCurve(closeSet, farSet []DistanceRecord) ([]ROCPoint, error) {
+	counts, err := sweepThresholds(closeSet, farSet)
+	if err != nil {
+		return nil, err
+	}
+	total := counts[len(counts)-1]
+	curve := make([]ROCPoint, 0, len(counts)+1)
+	curve = append(curve, ROCPoint{Threshold: math.Inf(-1)})
+	for _, count := range counts {
+		curve = append(curve, ROCPoint{
+			Threshold: count.threshold,
+			FPR:       float64(count.falsePositives) / float64(total.falsePositives),
+			TPR:       float64(count.truePositives) / float64(total.truePositives),
+		})
+	}
+	return curve, nil
+}
+
+// AUC returns the area under a ROC curve computed with the trapezoidal rule
+func AUC(curve []ROCPoint) float64 {
+	area := 0.
+	for i := 1; i < len(curve); i++ {
+		area += (curve[i].FPR - curve[i-1].FPR) * (curve[i].TPR + curve[i-1].TPR) / 2
+	}
+	return area
+}
+
+// PrecisionRecallCurve computes the precision-recall curve of the reduced
+// distances used to retrieve close pairs. Failed records are left out.
+func PrecisionRecallCurve(closeSet, farSet []DistanceRecord) ([]PRPoint, error) {
+	counts, err := sweepThresholds(closeSet, farSet)
+	if err != nil {
+		return nil, err
+	}
+	total := counts[len(counts)-1]
+	curve := make([]PRPoint, len(counts))
+	for i, count := range counts {
+		curve[i] = PRPoint{
+			Threshold: count.threshold,
+			Precision: float64(count.truePositives) / float64(count.truePositives+count.falsePositives),
+			Recall:    float64(count.truePositives) / float64(total.truePositives),
+		}
+	}
+	return curve, nil
+}
+
+// BestF1Threshold returns the reduced distance threshold maximizing the F1 score
+// of the close pairs prediction, along with that score
+func BestF1Threshold(closeSet, farSet []DistanceRecord) (float64, float64, error) {
+	curve, err := PrecisionRecallCurve(closeSet, farSet)
+	if err != nil {
+		return 0, 0, err
+	}
+	bestThreshold, bestF1 := math.NaN(), 0.
+	for _, point := range curve {
+		if point.Precision+point.Recall == 0 {
+			continue
+		}
+		f1 := 2 * point.Precision * point.Recall / (point.Precision + point.Recall)
+		if f1 > bestF1 {
+			bestThreshold, bestF1 = point.Threshold, f1
+		}
+	}
+	return bestThreshold, bestF1, nil
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// WriteROCCSV writes a ROC curve as CSV with the columns: threshold, fpr, tpr
+func WriteROCCSV(w io.Writer, curve []ROCPoint) error {
+	writer := bufio.NewWriter(w)
+	if _, err := fmt.Fprintln(writer, "threshold,fpr,tpr"); err != nil {
+		return err
+	}
+	for _, point := range curve {
+		_, err := fmt.Fprintf(writer, "%s,%s,%s\n", formatFloat(point.Threshold), formatFloat(point.FPR), formatFloat(point.TPR))
+		if err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
+
+// WritePRCSV writes a precision-recall curve as CSV with the columns: threshold, precision, recall
+func WritePRCSV(w io.Writer, curve []PRPoint) error {
+	writer := bufio.NewWriter(w)
+	if _, err := fmt.Fprintln(writer, "threshold,precision,recall"); err != nil {
+		return err
+	}
+	for _, point := range curve {
+		_, err := fmt.Fprintf(writer, "%s,%s,%s\n", formatFloat(point.Threshold), formatFloat(point.Precision), formatFloat(point.Recall))
+		if err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
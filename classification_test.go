@@ -0,0 +1,153 @@
+package reductions
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+var classificationCloseSet = []DistanceRecord{
+	{Key1: "k1", Key2: "k2", ReducedDistance: 0.1},
+	{Key1: "k3", Key2: "k4", ReducedDistance: 0.4},
+}
+
+var classificationFarSet = []DistanceRecord{
+	{Key1: "k5", Key2: "k6", ReducedDistance: 0.3},
+	{Key1: "k7", Key2: "k8", ReducedDistance: 0.5},
+}
+
+func TestROCCurve(t *testing.T) {
+	curve, err := ROCCurve(classificationCloseSet, classificationFarSet)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wanted := []ROCPoint{
+		{Threshold: math.Inf(-1), FPR: 0, TPR: 0},
+		{Threshold: 0.1, FPR: 0, TPR: 0.5},
+		{Threshold: 0.3, FPR: 0.5, TPR: 0.5},
+		{Threshold: 0.4, FPR: 0.5, TPR: 1},
+		{Threshold: 0.5, FPR: 1, TPR: 1},
+	}
+	if len(curve) != len(wanted) {
+		t.Fatalf("wanted %v got %v", wanted, curve)
+	}
+	for i := range wanted {
+		if curve[i] != wanted[i] {
+			t.Errorf("wanted %v got %v", wanted, curve)
+			break
+		}
+	}
+	if auc := AUC(curve); auc != 0.75 {
+		t.Errorf("wanted AUC 0.75, got %v", auc)
+	}
+}
+
+func TestROCCurveTies(t *testing.T) {
+	closeSet := []DistanceRecord{{ReducedDistance: 0.2}}
+	farSet := []DistanceRecord{{ReducedDistance: 0.2}}
+	curve, _ := ROCCurve(closeSet, farSet)
+	if len(curve) != 2 {
+		t.Errorf("tied distances should give a single threshold, got %v", curve)
+	}
+	if auc := AUC(curve); auc != 0.5 {
+		t.Errorf("wanted AUC 0.5 for tied distances, got %v", auc)
+	}
+}
+
+func TestClassificationFailedRecords(t *testing.T) {
+	closeSet := append([]DistanceRecord{
+		{Key1: "f1", Key2: "f2", Failed: true, RawDistance: math.NaN(), ReducedDistance: math.NaN()},
+	}, classificationCloseSet...)
+	farSet := append([]DistanceRecord{
+		{Key1: "f3", Key2: "f4", ReducedDistance: math.NaN()},
+	}, classificationFarSet...)
+
+	curve, err := ROCCurve(closeSet, farSet)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wanted, _ := ROCCurve(classificationCloseSet, classificationFarSet)
+	if len(curve) != len(wanted) {
+		t.Fatalf("wanted %v got %v", wanted, curve)
+	}
+	for i := range wanted {
+		if curve[i] != wanted[i] {
+			t.Errorf("wanted %v got %v", wanted, curve)
+			break
+		}
+	}
+
+	prCurve, err := PrecisionRecallCurve(closeSet, farSet)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, point := range prCurve {
+		if math.IsNaN(point.Threshold) || point.Recall > 1 {
+			t.Errorf("failed records should be left out, got %v", prCurve)
+			break
+		}
+	}
+
+	onlyFailed := []DistanceRecord{{Key1: "f1", Key2: "f2", Failed: true}}
+	if _, err := ROCCurve(onlyFailed, classificationFarSet); err == nil {
+		t.Errorf("expected an error when all close records failed")
+	}
+}
+
+func TestPrecisionRecallCurve(t *testing.T) {
+	curve, err := PrecisionRecallCurve(classificationCloseSet, classificationFarSet)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wanted := []PRPoint{
+		{Threshold: 0.1, Precision: 1, Recall: 0.5},
+		{Threshold: 0.3, Precision: 0.5, Recall: 0.5},
+		{Threshold: 0.4, Precision: 2. / 3., Recall: 1},
+		{Threshold: 0.5, Precision: 0.5, Recall: 1},
+	}
+	for i := range wanted {
+		if curve[i] != wanted[i] {
+			t.Errorf("wanted %v got %v", wanted, curve)
+			break
+		}
+	}
+
+	threshold, f1, err := BestF1Threshold(classificationCloseSet, classificationFarSet)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if threshold != 0.4 || math.Abs(f1-0.8) > 1e-12 {
+		t.Errorf("wanted threshold 0.4 and F1 0.8, got %v and %v", threshold, f1)
+	}
+}
+
+func TestClassificationErrors(t *testing.T) {
+	if _, err := ROCCurve(nil, classificationFarSet); err == nil {
+		t.Errorf("expected an error for an empty close set")
+	}
+	if _, err := PrecisionRecallCurve(classificationCloseSet, nil); err == nil {
+		t.Errorf("expected an error for an empty far set")
+	}
+}
+
+func TestWriteCurvesCSV(t *testing.T) {
+	roc, _ := ROCCurve(classificationCloseSet, classificationFarSet)
+	var buf bytes.Buffer
+	if err := WriteROCCSV(&buf, roc[:2]); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wanted := "threshold,fpr,tpr\n-Inf,0,0\n0.1,0,0.5\n"
+	if buf.String() != wanted {
+		t.Errorf("Wanted:\n%s\nGot:\n%s", wanted, buf.String())
+	}
+
+	pr, _ := PrecisionRecallCurve(classificationCloseSet, classificationFarSet)
+	buf.Reset()
+	if err := WritePRCSV(&buf, pr[:1]); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wanted = "threshold,precision,recall\n0.1,1,0.5\n"
+	if buf.String() != wanted {
+		t.Errorf("Wanted:\n%s\nGot:\n%s", wanted, buf.String())
+	}
+}
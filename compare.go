@@ -0,0 +1,231 @@
+package reductions
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// NamedReduction is a reduction function with a name identifying it in results
+type NamedReduction struct {
+	Name      string
+	Reduction func(string) string
+}
+
+// BaselineReductions returns the identity and homopolymer compression reductions
+func BaselineReductions() []NamedReduction {
+	return []NamedReduction{
+		{Name: "identity", Reduction: Identity},
+		{Name: "homopolymer_compression", Reduction: HomopolymerCompression},
+	}
+}
+
+// LoadSurjectionReduction reads a surjection .json file and makes a reduction
+// from it, named after the file
+func LoadSurjectionReduction(path string) (NamedReduction, error) {
+	var surjection map[string]string
+	if err := CheckSurjectionFile(path, &surjection); err != nil {
+		return NamedReduction{}, err
+	}
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return NamedReduction{Name: name, Reduction: MakeReductionFunction(surjection)}, nil
+}
+
+// ReductionComparison holds the evaluation of one reduction on a dataset.
+// Rank is 1 for the reduction with the lowest phi, reductions for which phi
+// is undefined (Err is set) are ranked last. CompressionRatio is the total
+// length of the reduced sequences divided by the total length of the raw ones.
+// Duration covers reducing the sequences and computing the reduced distances.
+type ReductionComparison struct {
+	Name             string
+	Rank             int
+	Phi              PhiRecord
+	Diagnostics      PhiDiagnostics
+	Summary          DistanceSummary
+	CompressionRatio float64
+	Duration         time.Duration
+	Err              error
+}
+
+// pairIndex returns the position of the pair (i, j), i < j, when all the pairs
+// of n sequences are listed in order
+func pairIndex(i, j, n int) int {
+	return i*n - i*(i+1)/2 + j - i - 1
+}
+
+// pairwiseDistances computes the Jaccard distances between all pairs of k-mer
+// sets, in pairIndex order. Pairs involving a set that could not be computed get
+// the corresponding error.
+func pairwiseDistances(ctx context.Context, sets []StringSet, errs []error, threads int) ([]float64, []error, error) {
+	n := len(sets)
+	distances := make([]float64, n*(n-1)/2)
+	pairErrs := make([]error, len(distances))
+	err := parallelFor(ctx, n, threads, func(i int) {
+		for j := i + 1; j < n; j++ {
+			index := pairIndex(i, j, n)
+			distances[index], pairErrs[index] = entryDistance(sets[i], sets[j], errs[i], errs[j])
+		}
+	})
+	return distances, pairErrs, err
+}
+
+// kmerizeSequences computes the k-mer sets of the given sequences in parallel
+func kmerizeSequences(ctx context.Context, seqKeys []string, sequences []string, k, threads int, reduced bool) ([]StringSet, []error, error) {
+	sets := make([]StringSet, len(sequences))
+	errs := make([]error, len(sequences))
+	err := parallelFor(ctx, len(sequences), threads, func(i int) {
+		sets[i], errs[i] = kmerizeChecked(seqKeys[i], sequences[i], k, reduced)
+	})
+	return sets, errs, err
+}
+
+// CompareReductions evaluates several reductions on the same dataset. Raw
+// distances are computed once and shared by all reductions, then each reduction
+// is applied once per sequence and its distances are evaluated with
+// ObjectivePhiChecked on the close and far sets given by split.
+// Pairs whose distances cannot be computed are handled according to policy;
+// FailOnError makes the whole comparison fail.
+// The results are sorted by rank.
+func CompareReductions(ctx context.Context, seqRecords map[string]string, k int, reductions []NamedReduction, split SetSplitter, threads int, policy DistanceErrorPolicy) ([]ReductionComparison, error) {
+	if threads < 1 {
+		threads = 1
+	}
+	seqKeys := make([]string, 0, len(seqRecords))
+	for key := range seqRecords {
+		seqKeys = append(seqKeys, key)
+	}
+	sort.Strings(seqKeys)
+
+	rawSequences := make([]string, len(seqKeys))
+	rawLength := 0
+	for i, key := range seqKeys {
+		rawSequences[i] = seqRecords[key]
+		rawLength += len(rawSequences[i])
+	}
+
+	rawSets, rawSetErrs, err := kmerizeSequences(ctx, seqKeys, rawSequences, k, threads, false)
+	if err != nil {
+		return nil, err
+	}
+	rawDistances, rawErrs, err := pairwiseDistances(ctx, rawSets, rawSetErrs, threads)
+	if err != nil {
+		return nil, err
+	}
+
+	comparisons := make([]ReductionComparison, len(reductions))
+	for r, reduction := range reductions {
+		start := time.Now()
+
+		reducedSequences := make([]string, len(seqKeys))
+		if err := parallelFor(ctx, len(seqKeys), threads, func(i int) {
+			reducedSequences[i] = reduction.Reduction(rawSequences[i])
+		}); err != nil {
+			return nil, err
+		}
+		reducedLength := 0
+		reducedRecords := make(map[string]string, len(seqKeys))
+		for i, key := range seqKeys {
+			reducedLength += len(reducedSequences[i])
+			reducedRecords[key] = reducedSequences[i]
+		}
+
+		reducedSets, reducedSetErrs, err := kmerizeSequences(ctx, seqKeys, reducedSequences, k, threads, true)
+		if err != nil {
+			return nil, err
+		}
+		reducedDistances, reducedErrs, err := pairwiseDistances(ctx, reducedSets, reducedSetErrs, threads)
+		if err != nil {
+			return nil, err
+		}
+
+		summary := shortReadsSummary(seqRecords, reducedRecords, k)
+		records := make([]DistanceRecord, 0, len(rawDistances))
+		for i := range seqKeys {
+			for j := i + 1; j < len(seqKeys); j++ {
+				index := pairIndex(i, j, len(seqKeys))
+				record := DistanceRecord{
+					Key1:            seqKeys[i],
+					Key2:            seqKeys[j],
+					RawDistance:     rawDistances[index],
+					ReducedDistance: reducedDistances[index],
+				}
+				pairErr := rawErrs[index]
+				if pairErr == nil {
+					pairErr = reducedErrs[index]
+				}
+				summary.Pairs++
+				if pairErr != nil {
+					switch policy {
+					case SkipOnError:
+						summary.Skipped++
+						continue
+					case FlagOnError:
+						summary.Flagged++
+						record.Failed = true
+					default:
+						return nil, fmt.Errorf("reduction %s: distance between %s and %s: %w", reduction.Name, record.Key1, record.Key2, pairErr)
+					}
+				}
+				records = append(records, record)
+			}
+		}
+
+		closeSet, farSet := split(records)
+		phi, diagnostics, err := ObjectivePhiChecked(closeSet, farSet)
+		comparisons[r] = ReductionComparison{
+			Name:             reduction.Name,
+			Phi:              phi,
+			Diagnostics:      diagnostics,
+			Summary:          summary,
+			CompressionRatio: float64(reducedLength) / float64(rawLength),
+			Duration:         time.Since(start),
+			Err:              err,
+		}
+	}
+
+	sort.SliceStable(comparisons, func(i, j int) bool {
+		if (comparisons[i].Err == nil) != (comparisons[j].Err == nil) {
+			return comparisons[i].Err == nil
+		}
+		return comparisons[i].Phi.Phi < comparisons[j].Phi.Phi
+	})
+	for i := range comparisons {
+		comparisons[i].Rank = i + 1
+	}
+	return comparisons, nil
+}
+
+// WriteComparisonTable writes the results of CompareReductions as a tab separated table
+func WriteComparisonTable(w io.Writer, comparisons []ReductionComparison) error {
+	writer := bufio.NewWriter(w)
+	header := "rank\treduction\tphi\tC\tF\tmu\tcompression_ratio\tseconds\tskipped\tflagged\terror"
+	if _, err := fmt.Fprintln(writer, header); err != nil {
+		return err
+	}
+	for _, comparison := range comparisons {
+		phi := comparison.Phi
+		errMessage := ""
+		if comparison.Err != nil {
+			nan := math.NaN()
+			phi = PhiRecord{Phi: nan, C: nan, F: nan, Mu: nan}
+			errMessage = comparison.Err.Error()
+		}
+		_, err := fmt.Fprintf(
+			writer, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%d\t%d\t%s\n",
+			comparison.Rank, comparison.Name,
+			formatFloat(phi.Phi), formatFloat(phi.C), formatFloat(phi.F), formatFloat(phi.Mu),
+			formatFloat(comparison.CompressionRatio), formatFloat(comparison.Duration.Seconds()),
+			comparison.Summary.Skipped, comparison.Summary.Flagged, errMessage,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
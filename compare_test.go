@@ -0,0 +1,126 @@
+package reductions
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// makeWFADataset builds sequences named like the WFA generate_dataset output,
+// each with a mutated "_err" copy
+func makeWFADataset(rng *rand.Rand, nSeqs, length int, rate float64) map[string]string {
+	seqs := map[string]string{}
+	for i := 0; i < nSeqs; i++ {
+		seq := randomSequence(rng, length)
+		seqs[fmt.Sprintf("seq_%d", i)] = seq
+		seqs[fmt.Sprintf("seq_%d_err", i)] = mutateSequence(rng, seq, rate)
+	}
+	return seqs
+}
+
+func TestCompareReductions(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	seqs := makeWFADataset(rng, 5, 200, 0.05)
+	comparisons, err := CompareReductions(context.Background(), seqs, 5, BaselineReductions(), MakeWFASequenceSets, 3, FailOnError)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(comparisons) != 2 {
+		t.Fatalf("wanted 2 comparisons got %d", len(comparisons))
+	}
+
+	for i, comparison := range comparisons {
+		if comparison.Rank != i+1 {
+			t.Errorf("comparisons should be sorted by rank: %v", comparisons)
+		}
+		reduction := Identity
+		if comparison.Name == "homopolymer_compression" {
+			reduction = HomopolymerCompression
+		}
+		distances, _, err := GetDistances(seqs, 5, reduction, FailOnError)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		wanted, _, _ := ObjectivePhiChecked(MakeWFASequenceSets(distances))
+		if math.Abs(wanted.Phi-comparison.Phi.Phi) > 1e-12 {
+			t.Errorf("%s: wanted %v got %v", comparison.Name, wanted, comparison.Phi)
+		}
+		if comparison.Summary.Pairs != 45 {
+			t.Errorf("%s: wanted 45 pairs, got %v", comparison.Name, comparison.Summary)
+		}
+		if comparison.Name == "identity" && comparison.CompressionRatio != 1 {
+			t.Errorf("identity should have a compression ratio of 1, got %v", comparison.CompressionRatio)
+		}
+		if comparison.Name == "homopolymer_compression" && comparison.CompressionRatio >= 1 {
+			t.Errorf("homopolymer compression should shrink sequences, got %v", comparison.CompressionRatio)
+		}
+	}
+	if comparisons[0].Phi.Phi > comparisons[1].Phi.Phi {
+		t.Errorf("comparisons should be sorted by phi: %v", comparisons)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteComparisonTable(&buf, comparisons); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 3 || !strings.HasPrefix(lines[1], "1\t") {
+		t.Errorf("unexpected table:\n%s", buf.String())
+	}
+}
+
+func TestCompareReductionsErrors(t *testing.T) {
+	seqs := map[string]string{
+		"seq_1":     "ATTGCATCAT",
+		"seq_1_err": "ATTGCATGAT",
+		"seq_2":     "AAATTTGGGC",
+		"seq_2_err": "AAATTTGGCC",
+	}
+	_, err := CompareReductions(context.Background(), seqs, 5, BaselineReductions(), MakeWFASequenceSets, 2, FailOnError)
+	if !errors.Is(err, ErrReadTooShort) {
+		t.Errorf("expected a short read error, got %v", err)
+	}
+
+	comparisons, err := CompareReductions(context.Background(), seqs, 5, BaselineReductions(), MakeWFASequenceSets, 2, SkipOnError)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, comparison := range comparisons {
+		if comparison.Name == "homopolymer_compression" && comparison.Summary.Skipped != 5 {
+			t.Errorf("expected 5 skipped pairs, got %v", comparison.Summary)
+		}
+	}
+}
+
+func TestLoadSurjectionReduction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hpc_order2.json")
+	mapping := `{
+		"AA": ".", "AC": "C", "AG": "G", "AT": "T",
+		"CA": "A", "CC": ".", "CG": "G", "CT": "T",
+		"GA": "A", "GC": "C", "GG": ".", "GT": "T",
+		"TA": "A", "TC": "C", "TG": "G", "TT": "."
+	}`
+	if err := ioutil.WriteFile(path, []byte(mapping), 0644); err != nil {
+		t.Fatal(err)
+	}
+	reduction, err := LoadSurjectionReduction(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if reduction.Name != "hpc_order2" {
+		t.Errorf("wanted name hpc_order2, got %s", reduction.Name)
+	}
+	if reduced := reduction.Reduction("AAATTGGC"); reduced != "ATGC" {
+		t.Errorf("wanted ATGC got %s", reduced)
+	}
+
+	if _, err := LoadSurjectionReduction(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Errorf("expected an error for a missing file")
+	}
+}
@@ -0,0 +1,89 @@
+package reductions
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hillbig/rsdic"
+)
+
+// ErrPositionOutOfRange is returned when lifting a position outside of a read
+var ErrPositionOutOfRange = errors.New("position out of range")
+
+// CoordinateMap links positions of a raw read to positions of its reduced read. It is
+// backed by a bit vector with one bit per raw position, set if the position is kept.
+// It assumes every kept position produces exactly one character of the reduced read,
+// which holds for mappings with outputs of size 1.
+type CoordinateMap struct {
+	kept *rsdic.RSDic
+}
+
+// NewCoordinateMap makes a coordinate map from the bit vector returned by
+// MakeReductionFunctionBitVector
+func NewCoordinateMap(kept *rsdic.RSDic) *CoordinateMap {
+	return &CoordinateMap{kept: kept}
+}
+
+// ParseCoordinateMap makes a coordinate map from the offsets returned by
+// MakeReductionFunctionKeepOffsets (e.g. "M1D4M2") or from a CIGAR, see ParseOffsets
+func ParseCoordinateMap(offsets string) (*CoordinateMap, error) {
+	ops, err := ParseOffsets(offsets)
+	if err != nil {
+		return nil, err
+	}
+	return NewCoordinateMap(ops.BitVector()), nil
+}
+
+// RawLength returns the length of the raw read
+func (m *CoordinateMap) RawLength() int {
+	return int(m.kept.Num())
+}
+
+// ReducedLength returns the length of the reduced read
+func (m *CoordinateMap) ReducedLength() int {
+	return int(m.kept.OneNum())
+}
+
+// Kept returns the bit vector of kept raw positions
+func (m *CoordinateMap) Kept() *rsdic.RSDic {
+	return m.kept
+}
+
+// RawToReduced returns the reduced position of a raw position, and whether the raw
+// position was kept. Deleted positions are lifted to the next kept position.
+func (m *CoordinateMap) RawToReduced(pos int) (int, bool, error) {
+	if pos < 0 || pos >= m.RawLength() {
+		return 0, false, fmt.Errorf("raw position %d of a read of length %d: %w", pos, m.RawLength(), ErrPositionOutOfRange)
+	}
+	return int(m.kept.Rank(uint64(pos), true)), m.kept.Bit(uint64(pos)), nil
+}
+
+// ReducedToRaw returns the raw position of a reduced position
+func (m *CoordinateMap) ReducedToRaw(pos int) (int, error) {
+	if pos < 0 || pos >= m.ReducedLength() {
+		return 0, fmt.Errorf("reduced position %d of a read of length %d: %w", pos, m.ReducedLength(), ErrPositionOutOfRange)
+	}
+	return int(m.kept.Select(uint64(pos), true)), nil
+}
+
+// RawIntervalToReduced lifts the half-open raw interval [start, end) to the reduced
+// interval made of the kept positions it contains
+func (m *CoordinateMap) RawIntervalToReduced(start, end int) (int, int, error) {
+	if start < 0 || start > end || end > m.RawLength() {
+		return 0, 0, fmt.Errorf("raw interval [%d, %d) of a read of length %d: %w", start, end, m.RawLength(), ErrPositionOutOfRange)
+	}
+	return int(m.kept.Rank(uint64(start), true)), int(m.kept.Rank(uint64(end), true)), nil
+}
+
+// ReducedIntervalToRaw lifts the half-open reduced interval [start, end) to the smallest
+// raw interval containing the raw positions of its characters. An empty interval is
+// lifted to an empty interval at the raw position of start.
+func (m *CoordinateMap) ReducedIntervalToRaw(start, end int) (int, int, error) {
+	if start < 0 || start > end || end > m.ReducedLength() {
+		return 0, 0, fmt.Errorf("reduced interval [%d, %d) of a read of length %d: %w", start, end, m.ReducedLength(), ErrPositionOutOfRange)
+	}
+	rawStart := int(m.kept.Select(uint64(start), true))
+	if start == end {
+		return rawStart, rawStart, nil
+	}
+	return rawStart, int(m.kept.Select(uint64(end-1), true)) + 1, nil
+}
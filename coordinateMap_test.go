@@ -0,0 +1,121 @@
+package reductions
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+func TestCoordinateMapFromReduction(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	read := randomSequence(rng, 300)
+	reduced, bits := MakeReductionFunctionBitVector(hpcSurjection)(read)
+	reducedOffsets, offsets := MakeReductionFunctionKeepOffsets(hpcSurjection)(read)
+	if reduced != reducedOffsets {
+		t.Fatalf("%s (bit vector)\n%s (offsets)", reduced, reducedOffsets)
+	}
+	parsed, err := ParseCoordinateMap(offsets)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, m := range []*CoordinateMap{NewCoordinateMap(bits), parsed} {
+		if m.RawLength() != len(read) || m.ReducedLength() != len(reduced) {
+			t.Fatalf("%d, %d (got lengths)\n%d, %d (wanted)", m.RawLength(), m.ReducedLength(), len(read), len(reduced))
+		}
+		for i := 0; i < len(reduced); i++ {
+			raw, err := m.ReducedToRaw(i)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if read[raw] != reduced[i] {
+				t.Errorf("reduced %d lifted to raw %d: %c != %c", i, raw, reduced[i], read[raw])
+			}
+			back, kept, err := m.RawToReduced(raw)
+			if err != nil || !kept || back != i {
+				t.Errorf("%d, %v, %v (got)\n%d, true (wanted)", back, kept, err, i)
+			}
+		}
+	}
+}
+
+func TestCoordinateMapLifting(t *testing.T) {
+	// raw:     0 1 2 3 4 5 6 7
+	// kept:    M D D D D M M D
+	// reduced: 0         1 2
+	m, err := ParseCoordinateMap("M1D4M2D1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var rawTests = []struct {
+		pos, wanted int
+		kept        bool
+	}{
+		{pos: 0, wanted: 0, kept: true},
+		{pos: 1, wanted: 1, kept: false},
+		{pos: 4, wanted: 1, kept: false},
+		{pos: 5, wanted: 1, kept: true},
+		{pos: 6, wanted: 2, kept: true},
+		{pos: 7, wanted: 3, kept: false},
+	}
+	for _, testCase := range rawTests {
+		ans, kept, err := m.RawToReduced(testCase.pos)
+		if err != nil || ans != testCase.wanted || kept != testCase.kept {
+			t.Errorf("RawToReduced(%d) = %d, %v, %v\nwanted %d, %v", testCase.pos, ans, kept, err, testCase.wanted, testCase.kept)
+		}
+	}
+
+	var intervalTests = []struct {
+		name               string
+		reduced            bool
+		start, end         int
+		wantStart, wantEnd int
+	}{
+		{name: "RawAll", start: 0, end: 8, wantStart: 0, wantEnd: 3},
+		{name: "RawDeleted", start: 1, end: 5, wantStart: 1, wantEnd: 1},
+		{name: "RawPartial", start: 3, end: 6, wantStart: 1, wantEnd: 2},
+		{name: "ReducedAll", reduced: true, start: 0, end: 3, wantStart: 0, wantEnd: 7},
+		{name: "ReducedSkipsDeletion", reduced: true, start: 0, end: 2, wantStart: 0, wantEnd: 6},
+		{name: "ReducedLast", reduced: true, start: 2, end: 3, wantStart: 6, wantEnd: 7},
+		{name: "ReducedEmpty", reduced: true, start: 1, end: 1, wantStart: 5, wantEnd: 5},
+		{name: "ReducedEmptyAtEnd", reduced: true, start: 3, end: 3, wantStart: 8, wantEnd: 8},
+	}
+	for _, testCase := range intervalTests {
+		t.Run(testCase.name, func(t *testing.T) {
+			lift := m.RawIntervalToReduced
+			if testCase.reduced {
+				lift = m.ReducedIntervalToRaw
+			}
+			start, end, err := lift(testCase.start, testCase.end)
+			if err != nil || start != testCase.wantStart || end != testCase.wantEnd {
+				t.Errorf("[%d, %d), %v (got)\n[%d, %d) (wanted)", start, end, err, testCase.wantStart, testCase.wantEnd)
+			}
+		})
+	}
+}
+
+func TestCoordinateMapErrors(t *testing.T) {
+	m, err := ParseCoordinateMap("M2D1M1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err := m.RawToReduced(4); !errors.Is(err, ErrPositionOutOfRange) {
+		t.Errorf("Was expecting ErrPositionOutOfRange, got %v", err)
+	}
+	if _, err := m.ReducedToRaw(3); !errors.Is(err, ErrPositionOutOfRange) {
+		t.Errorf("Was expecting ErrPositionOutOfRange, got %v", err)
+	}
+	if _, _, err := m.RawIntervalToReduced(2, 1); !errors.Is(err, ErrPositionOutOfRange) {
+		t.Errorf("Was expecting ErrPositionOutOfRange, got %v", err)
+	}
+	if _, _, err := m.ReducedIntervalToRaw(0, 4); !errors.Is(err, ErrPositionOutOfRange) {
+		t.Errorf("Was expecting ErrPositionOutOfRange, got %v", err)
+	}
+
+	for _, offsets := range []string{"X3", "M", "MD2", "M-1"} {
+		if _, err := ParseCoordinateMap(offsets); err == nil {
+			t.Errorf("Was expecting error when parsing %q", offsets)
+		}
+	}
+}
@@ -1,17 +1,72 @@
 package reductions
 
 import (
+	"context"
 	"fmt"
 	"gonum.org/v1/gonum/stat/combin"
+	"math"
+	"runtime"
 	"sort"
 	"strings"
 	"sync"
 )
 
-// DistanceRecord keeps the distance between 2 sequences with the given keys
+// DistanceRecord keeps the distance between 2 sequences with the given keys.
+// Failed is set when one of the distances could not be computed, in which case
+// it is set to NaN.
 type DistanceRecord struct {
 	Key1, Key2                   string
 	RawDistance, ReducedDistance float64
+	Failed                       bool
+}
+
+// DistanceErrorPolicy sets how pairs for which a distance cannot be computed are handled
+type DistanceErrorPolicy int
+
+const (
+	// FailOnError stops the computation and returns the first error
+	FailOnError DistanceErrorPolicy = iota
+	// SkipOnError leaves the pair out of the returned records
+	SkipOnError
+	// FlagOnError keeps the pair with NaN distances and the Failed flag set
+	FlagOnError
+)
+
+// DistanceSummary counts the pairs affected by errors during a distance computation
+type DistanceSummary struct {
+	Pairs, Skipped, Flagged int
+	// ShortRawReads lists the sequences shorter than k,
+	// ShortReducedReads the ones that became shorter than k after reduction
+	ShortRawReads, ShortReducedReads []string
+}
+
+// String implements the Stringer interface for DistanceSummary structs
+func (summary DistanceSummary) String() string {
+	return fmt.Sprintf(
+		"{pairs: %d, skipped: %d, flagged: %d, short raw reads: %d, short reduced reads: %d}",
+		summary.Pairs, summary.Skipped, summary.Flagged,
+		len(summary.ShortRawReads), len(summary.ShortReducedReads),
+	)
+}
+
+// ShortReadError is returned when a sequence is too short to be kmerized
+type ShortReadError struct {
+	Key       string
+	Length, K int
+	Reduced   bool
+}
+
+// Error implements the error interface for ShortReadError
+func (e *ShortReadError) Error() string {
+	if e.Reduced {
+		return fmt.Sprintf("reduced read %s is shorter than k (%d < %d)", e.Key, e.Length, e.K)
+	}
+	return fmt.Sprintf("read %s is shorter than k (%d < %d)", e.Key, e.Length, e.K)
+}
+
+// Unwrap makes ShortReadError match ErrReadTooShort with errors.Is
+func (e *ShortReadError) Unwrap() error {
+	return ErrReadTooShort
 }
 
 // String implements the Stringer interface for DistanceRecord structs
@@ -32,6 +87,7 @@ func sortRecordKeys(record DistanceRecord) DistanceRecord {
 		Key2:            record.Key1,
 		RawDistance:     record.RawDistance,
 		ReducedDistance: record.ReducedDistance,
+		Failed:          record.Failed,
 	}
 }
 
@@ -49,7 +105,13 @@ func AreDistanceRecordSlicesEqual(a, b []DistanceRecord) bool {
 
 	comp := func(i, j int, recs []DistanceRecord) bool {
 		r1, r2 := recs[i], recs[j]
-		return r1.RawDistance < r2.RawDistance
+		if r1.RawDistance != r2.RawDistance {
+			return r1.RawDistance < r2.RawDistance
+		}
+		if r1.Key1 != r2.Key1 {
+			return r1.Key1 < r2.Key1
+		}
+		return r1.Key2 < r2.Key2
 	}
 
 	sort.SliceStable(aReord, func(i, j int) bool { return comp(i, j, aReord) })
@@ -83,8 +145,20 @@ func JaccardSimilarity(set1, set2 StringSet) float64 {
 	if len(set1) == 0 || len(set2) == 0 {
 		return 0.0
 	}
-	interLen := len(set1.Intersection(set2))
-	unionLen := len(set1.Union(set2))
+	interLen := set1.IntersectionLen(set2)
+	unionLen := len(set1) + len(set2) - interLen
+
+	return float64(interLen) / float64(unionLen)
+}
+
+// JaccardSimilarityUint64 returns the Jaccard index between two
+// sets of encoded k-mers.
+func JaccardSimilarityUint64(set1, set2 Uint64Set) float64 {
+	if len(set1) == 0 || len(set2) == 0 {
+		return 0.0
+	}
+	interLen := set1.IntersectionLen(set2)
+	unionLen := len(set1) + len(set2) - interLen
 
 	return float64(interLen) / float64(unionLen)
 }
@@ -104,119 +178,241 @@ func KmerizedJaccardDistance(seq1, seq2 string, k int) (float64, error) {
 	return 1. - JaccardSimilarity(kmers1, kmers2), nil
 }
 
-// GetDistances computes distances between all pairs of strings in a list
-func GetDistances(seqRecords map[string]string, k int, reduction func(string) string) []DistanceRecord {
-	seqKeys := make([]string, 0, len(seqRecords))
-	for key := range seqRecords {
-		seqKeys = append(seqKeys, key)
+// GetDistances computes distances between all pairs of strings in a list,
+// using as many workers as there are CPUs
+func GetDistances(seqRecords map[string]string, k int, reduction func(string) string, policy DistanceErrorPolicy) ([]DistanceRecord, DistanceSummary, error) {
+	return GetDistancesMultiThread(context.Background(), seqRecords, k, reduction, runtime.NumCPU(), policy)
+}
+
+// pairJob holds the keys of a pair of sequences to compare
+type pairJob struct {
+	key1, key2 string
+}
+
+// pairResult holds the outcome of the comparison of a pair of sequences
+type pairResult struct {
+	record DistanceRecord
+	err    error
+}
+
+// computePairDistance computes the raw and reduced distances between 2 sequences.
+// The returned record always holds the keys and has NaN distances where an error occurred.
+func computePairDistance(job pairJob, rawRecords, reducedRecords map[string]string, k int) (DistanceRecord, error) {
+	record := DistanceRecord{
+		Key1:            job.key1,
+		Key2:            job.key2,
+		RawDistance:     math.NaN(),
+		ReducedDistance: math.NaN(),
 	}
 
-	nRecords := combin.Binomial(len(seqKeys), 2)
-	distances := make([]DistanceRecord, 0, nRecords)
+	rawDist, err := kmerizedPairDistance(job, rawRecords, k, false)
+	if err != nil {
+		return record, fmt.Errorf("raw distance between %s and %s: %w", job.key1, job.key2, err)
+	}
+	record.RawDistance = rawDist
 
-	queue := make(chan DistanceRecord, 1)
-	var wg sync.WaitGroup
+	redDist, err := kmerizedPairDistance(job, reducedRecords, k, true)
+	if err != nil {
+		return record, fmt.Errorf("reduced distance between %s and %s: %w", job.key1, job.key2, err)
+	}
+	record.ReducedDistance = redDist
 
-	for i, key1 := range seqKeys {
-		for _, key2 := range seqKeys[i+1:] {
-			wg.Add(1)
-			go func(key1, key2 string) {
-				rawDist, _ := KmerizedJaccardDistance(seqRecords[key1], seqRecords[key2], k)
-				redDist, _ := KmerizedJaccardDistance(reduction(seqRecords[key1]), reduction(seqRecords[key2]), k)
-				queue <- DistanceRecord{
-					Key1:            key1,
-					Key2:            key2,
-					RawDistance:     rawDist,
-					ReducedDistance: redDist,
-				}
-			}(key1, key2)
+	return record, nil
+}
+
+// kmerizedPairDistance returns the Jaccard distance of a pair of sequences, with
+// a ShortReadError if any of them is shorter than k
+func kmerizedPairDistance(job pairJob, seqRecords map[string]string, k int, reduced bool) (float64, error) {
+	for _, key := range []string{job.key1, job.key2} {
+		if len(seqRecords[key]) < k {
+			return 0, &ShortReadError{Key: key, Length: len(seqRecords[key]), K: k, Reduced: reduced}
+		}
+	}
+	return KmerizedJaccardDistance(seqRecords[job.key1], seqRecords[job.key2], k)
+}
+
+func distanceWorker(ctx context.Context, jobs <-chan pairJob, results chan<- pairResult, rawRecords, reducedRecords map[string]string, k int) {
+	for job := range jobs {
+		if ctx.Err() != nil {
+			return
+		}
+		record, err := computePairDistance(job, rawRecords, reducedRecords, k)
+		select {
+		case results <- pairResult{record: record, err: err}:
+		case <-ctx.Done():
+			return
 		}
 	}
+}
 
-	go func() {
-		for record := range queue {
-			distances = append(distances, record)
-			wg.Done()
+// producePairs sends all the pairs of keys to the jobs channel, stopping
+// early if the context is cancelled
+func producePairs(ctx context.Context, seqKeys []string, jobs chan<- pairJob) {
+	defer close(jobs)
+	for i, key1 := range seqKeys {
+		for _, key2 := range seqKeys[i+1:] {
+			select {
+			case jobs <- pairJob{key1: key1, key2: key2}:
+			case <-ctx.Done():
+				return
+			}
 		}
-	}()
+	}
+}
 
-	wg.Wait()
-	return distances
+// reduceSequences applies the reduction once to every sequence
+func reduceSequences(seqRecords map[string]string, reduction func(string) string) map[string]string {
+	reduced := make(map[string]string, len(seqRecords))
+	for key, seq := range seqRecords {
+		reduced[key] = reduction(seq)
+	}
+	return reduced
 }
 
-func distanceWorker(id int, jobs <-chan []string, results chan<- DistanceRecord, seqRecords map[string]string, k int, reduction func(string) string) {
-	for job := range jobs {
-		rawDist, _ := KmerizedJaccardDistance(seqRecords[job[0]], seqRecords[job[1]], k)
-		redDist, _ := KmerizedJaccardDistance(reduction(seqRecords[job[0]]), reduction(seqRecords[job[1]]), k)
-		results <- DistanceRecord{
-			Key1:            job[0],
-			Key2:            job[1],
-			RawDistance:     rawDist,
-			ReducedDistance: redDist,
+// shortReadsSummary lists the sequences that are shorter than k before and after reduction
+func shortReadsSummary(seqRecords, reduced map[string]string, k int) DistanceSummary {
+	summary := DistanceSummary{}
+	for key, seq := range seqRecords {
+		if len(seq) < k {
+			summary.ShortRawReads = append(summary.ShortRawReads, key)
+		} else if len(reduced[key]) < k {
+			summary.ShortReducedReads = append(summary.ShortReducedReads, key)
 		}
 	}
+	sort.Strings(summary.ShortRawReads)
+	sort.Strings(summary.ShortReducedReads)
+	return summary
 }
 
 // GetDistancesMultiThread computes distances between all pairs of strings in a list
-func GetDistancesMultiThread(seqRecords map[string]string, k int, reduction func(string) string, threads int) []DistanceRecord {
+// using at most threads worker goroutines. Pairs are streamed to the workers so memory
+// does not grow with the number of pairs beyond the returned slice.
+// Pairs whose distances cannot be computed are handled according to policy, and
+// counted in the returned summary. With FailOnError the first error stops the
+// computation and is returned. The context error is returned if ctx is cancelled
+// before all pairs are processed.
+func GetDistancesMultiThread(ctx context.Context, seqRecords map[string]string, k int, reduction func(string) string, threads int, policy DistanceErrorPolicy) ([]DistanceRecord, DistanceSummary, error) {
 	seqKeys := make([]string, 0, len(seqRecords))
 	for key := range seqRecords {
 		seqKeys = append(seqKeys, key)
 	}
 
+	reducedRecords := reduceSequences(seqRecords, reduction)
+	summary := shortReadsSummary(seqRecords, reducedRecords, k)
+	if len(seqKeys) < 2 {
+		return []DistanceRecord{}, summary, nil
+	}
+
 	nRecords := combin.Binomial(len(seqKeys), 2)
 	distances := make([]DistanceRecord, 0, nRecords)
 
 	if nRecords < threads {
 		threads = nRecords
 	}
+	if threads < 1 {
+		threads = 1
+	}
+
+	workerCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
-	results := make(chan DistanceRecord, nRecords)
-	keyChannel := make(chan []string, nRecords)
+	jobs := make(chan pairJob, threads)
+	results := make(chan pairResult, threads)
 
-	for w := 1; w <= nRecords; w++ {
-		go distanceWorker(w, keyChannel, results, seqRecords, k, reduction)
+	go producePairs(workerCtx, seqKeys, jobs)
+
+	var wg sync.WaitGroup
+	for w := 0; w < threads; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			distanceWorker(workerCtx, jobs, results, seqRecords, reducedRecords, k)
+		}()
 	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
 
-	for i, key1 := range seqKeys {
-		for _, key2 := range seqKeys[i+1:] {
-			keyChannel <- []string{key1, key2}
+	var firstErr error
+	for result := range results {
+		summary.Pairs++
+		if result.err != nil {
+			switch policy {
+			case SkipOnError:
+				summary.Skipped++
+				continue
+			case FlagOnError:
+				summary.Flagged++
+				result.record.Failed = true
+			default:
+				if firstErr == nil {
+					firstErr = result.err
+					cancel()
+				}
+				continue
+			}
 		}
+		distances = append(distances, result.record)
+	}
+
+	if firstErr != nil {
+		return nil, summary, firstErr
 	}
+	if err := ctx.Err(); err != nil {
+		return nil, summary, err
+	}
+	return distances, summary, nil
+}
 
-	close(keyChannel)
+// FilterFailedRecords separates the records whose distances were computed
+// from the ones flagged as failed
+func FilterFailedRecords(distances []DistanceRecord) ([]DistanceRecord, []DistanceRecord) {
+	valid := []DistanceRecord{}
+	failed := []DistanceRecord{}
+	for _, record := range distances {
+		if record.Failed {
+			failed = append(failed, record)
+		} else {
+			valid = append(valid, record)
+		}
+	}
+	return valid, failed
+}
 
-	for r := 1; r <= nRecords; r++ {
-		distances = append(distances, <-results)
+// RadiusCloseness returns a function that considers a pair close if its raw
+// distance is <= radius
+func RadiusCloseness(radius float64) func(DistanceRecord) bool {
+	return func(record DistanceRecord) bool {
+		return record.RawDistance <= radius
 	}
+}
 
-	return distances
+// IsWFAClose returns true if the pair is made of a sequence from the WFA
+// generate_dataset tool and its mutated version
+func IsWFAClose(record DistanceRecord) bool {
+	return strings.Replace(record.Key1, "_err", "", -1) ==
+		strings.Replace(record.Key2, "_err", "", -1)
 }
 
 // MakeSequenceSets separates a set of sequences distances into the set of close sequences (dist <= radius)
 // and the set of far sequences (dist > radius) and returns them both
 func MakeSequenceSets(distances []DistanceRecord, radius float64) ([]DistanceRecord, []DistanceRecord) {
-	closeSet := []DistanceRecord{}
-	farSet := []DistanceRecord{}
-
-	for _, record := range distances {
-		if record.RawDistance <= radius {
-			closeSet = append(closeSet, record)
-		} else {
-			farSet = append(farSet, record)
-		}
-	}
-	return closeSet, farSet
+	return splitSequenceSets(distances, RadiusCloseness(radius))
 }
 
 // MakeWFASequenceSets generates a "close" and a "far" sequence set from the WFA generate_dataset sequences
 func MakeWFASequenceSets(distances []DistanceRecord) ([]DistanceRecord, []DistanceRecord) {
+	return splitSequenceSets(distances, IsWFAClose)
+}
+
+// splitSequenceSets separates distances into the close and far sets with the given predicate
+func splitSequenceSets(distances []DistanceRecord, isClose func(DistanceRecord) bool) ([]DistanceRecord, []DistanceRecord) {
 	closeSet := []DistanceRecord{}
 	farSet := []DistanceRecord{}
 
 	for _, record := range distances {
-		if strings.Replace(record.Key1, "_err", "", -1) ==
-			strings.Replace(record.Key2, "_err", "", -1) {
+		if isClose(record) {
 			closeSet = append(closeSet, record)
 		} else {
 			farSet = append(farSet, record)
@@ -0,0 +1,243 @@
+package reductions
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DistanceMatrix stores the raw and reduced distances between all pairs of
+// sequences, with the sequence keys in lexicographical order. Failed holds the
+// Failed flag of the DistanceRecord of each pair.
+type DistanceMatrix struct {
+	Keys         []string
+	Raw, Reduced [][]float64
+	Failed       [][]bool
+	index        map[string]int
+}
+
+// newEmptyDistanceMatrix creates a matrix for the given keys where all
+// off-diagonal distances are NaN
+func newEmptyDistanceMatrix(keys []string) *DistanceMatrix {
+	sort.Strings(keys)
+	matrix := &DistanceMatrix{
+		Keys:    keys,
+		Raw:     make([][]float64, len(keys)),
+		Reduced: make([][]float64, len(keys)),
+		Failed:  make([][]bool, len(keys)),
+		index:   make(map[string]int, len(keys)),
+	}
+	for i, key := range keys {
+		matrix.index[key] = i
+		matrix.Raw[i] = make([]float64, len(keys))
+		matrix.Reduced[i] = make([]float64, len(keys))
+		matrix.Failed[i] = make([]bool, len(keys))
+		for j := range keys {
+			if i != j {
+				matrix.Raw[i][j] = math.NaN()
+				matrix.Reduced[i][j] = math.NaN()
+			}
+		}
+	}
+	return matrix
+}
+
+// isSet returns true if a record was stored for the pair (i, j)
+func (matrix *DistanceMatrix) isSet(i, j int) bool {
+	return matrix.Failed[i][j] || !math.IsNaN(matrix.Raw[i][j]) || !math.IsNaN(matrix.Reduced[i][j])
+}
+
+// sameDistance returns true if 2 distances are equal or both NaN
+func sameDistance(a, b float64) bool {
+	return a == b || (math.IsNaN(a) && math.IsNaN(b))
+}
+
+// set stores the distances of a record in the matrix, returning an error
+// if the pair was already set to different values
+func (matrix *DistanceMatrix) set(record DistanceRecord) error {
+	i, j := matrix.index[record.Key1], matrix.index[record.Key2]
+	if i == j {
+		return fmt.Errorf("distance record between %s and itself", record.Key1)
+	}
+	if matrix.isSet(i, j) && (!sameDistance(matrix.Raw[i][j], record.RawDistance) ||
+		!sameDistance(matrix.Reduced[i][j], record.ReducedDistance) ||
+		matrix.Failed[i][j] != record.Failed) {
+		return fmt.Errorf("conflicting distance records for pair %s, %s", record.Key1, record.Key2)
+	}
+	matrix.Raw[i][j], matrix.Raw[j][i] = record.RawDistance, record.RawDistance
+	matrix.Reduced[i][j], matrix.Reduced[j][i] = record.ReducedDistance, record.ReducedDistance
+	matrix.Failed[i][j], matrix.Failed[j][i] = record.Failed, record.Failed
+	return nil
+}
+
+// NewDistanceMatrix builds a distance matrix from a slice of DistanceRecords.
+// Pairs that have no record are set to NaN.
+func NewDistanceMatrix(records []DistanceRecord) (*DistanceMatrix, error) {
+	keySet := StringSet{}
+	for _, record := range records {
+		keySet.Add(record.Key1)
+		keySet.Add(record.Key2)
+	}
+
+	matrix := newEmptyDistanceMatrix(keySet.Sorted())
+	for _, record := range records {
+		if err := matrix.set(record); err != nil {
+			return nil, err
+		}
+	}
+	return matrix, nil
+}
+
+// Get returns the raw and reduced distances between 2 sequences
+func (matrix *DistanceMatrix) Get(key1, key2 string) (float64, float64, bool) {
+	i, ok1 := matrix.index[key1]
+	j, ok2 := matrix.index[key2]
+	if !ok1 || !ok2 {
+		return 0, 0, false
+	}
+	return matrix.Raw[i][j], matrix.Reduced[i][j], true
+}
+
+// Records returns the DistanceRecords of all the pairs in the matrix, ordered
+// by their keys. Pairs without a record are left out, failed ones are kept.
+func (matrix *DistanceMatrix) Records() []DistanceRecord {
+	records := make([]DistanceRecord, 0, len(matrix.Keys)*(len(matrix.Keys)-1)/2)
+	for i, key1 := range matrix.Keys {
+		for j := i + 1; j < len(matrix.Keys); j++ {
+			if !matrix.isSet(i, j) {
+				continue
+			}
+			records = append(records, DistanceRecord{
+				Key1:            key1,
+				Key2:            matrix.Keys[j],
+				RawDistance:     matrix.Raw[i][j],
+				ReducedDistance: matrix.Reduced[i][j],
+				Failed:          matrix.Failed[i][j],
+			})
+		}
+	}
+	return records
+}
+
+func (matrix *DistanceMatrix) values(reduced bool) [][]float64 {
+	if reduced {
+		return matrix.Reduced
+	}
+	return matrix.Raw
+}
+
+// WritePhylip writes the raw or reduced distances as a (relaxed) PHYLIP
+// distance matrix
+func (matrix *DistanceMatrix) WritePhylip(w io.Writer, reduced bool) error {
+	writer := bufio.NewWriter(w)
+	if _, err := fmt.Fprintf(writer, "%d\n", len(matrix.Keys)); err != nil {
+		return err
+	}
+	for i, row := range matrix.values(reduced) {
+		if _, err := writer.WriteString(matrix.Keys[i]); err != nil {
+			return err
+		}
+		for _, value := range row {
+			if _, err := fmt.Fprintf(writer, " %.6f", value); err != nil {
+				return err
+			}
+		}
+		if err := writer.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
+
+// WriteSquareTSV writes the raw or reduced distances as a square tab
+// separated matrix with the keys as header and first column
+func (matrix *DistanceMatrix) WriteSquareTSV(w io.Writer, reduced bool) error {
+	writer := bufio.NewWriter(w)
+	if _, err := fmt.Fprintf(writer, "\t%s\n", strings.Join(matrix.Keys, "\t")); err != nil {
+		return err
+	}
+	for i, row := range matrix.values(reduced) {
+		fields := make([]string, len(row)+1)
+		fields[0] = matrix.Keys[i]
+		for j, value := range row {
+			fields[j+1] = strconv.FormatFloat(value, 'g', -1, 64)
+		}
+		if _, err := fmt.Fprintln(writer, strings.Join(fields, "\t")); err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
+
+// WriteLongTSV writes one line per pair of sequences with the columns:
+// key1, key2, raw distance, reduced distance and failed flag. Distances are
+// written at full precision so the file can be read back with ReadLongTSV.
+func (matrix *DistanceMatrix) WriteLongTSV(w io.Writer) error {
+	writer := bufio.NewWriter(w)
+	if _, err := fmt.Fprintln(writer, "key1\tkey2\traw\treduced\tfailed"); err != nil {
+		return err
+	}
+	for _, record := range matrix.Records() {
+		_, err := fmt.Fprintf(
+			writer, "%s\t%s\t%s\t%s\t%t\n",
+			record.Key1, record.Key2,
+			strconv.FormatFloat(record.RawDistance, 'g', -1, 64),
+			strconv.FormatFloat(record.ReducedDistance, 'g', -1, 64),
+			record.Failed,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
+
+// ReadLongTSV reads a distance matrix written by WriteLongTSV. The failed
+// column may be missing, in which case no record is flagged as failed.
+func ReadLongTSV(r io.Reader) (*DistanceMatrix, error) {
+	scanner := bufio.NewScanner(r)
+	records := []DistanceRecord{}
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || (line == 1 && strings.HasPrefix(text, "key1\t")) {
+			continue
+		}
+		fields := strings.Split(text, "\t")
+		if len(fields) != 4 && len(fields) != 5 {
+			return nil, fmt.Errorf("line %d: expected 4 or 5 fields, got %d", line, len(fields))
+		}
+		raw, err := strconv.ParseFloat(fields[2], 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		reduced, err := strconv.ParseFloat(fields[3], 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		failed := false
+		if len(fields) == 5 {
+			if failed, err = strconv.ParseBool(fields[4]); err != nil {
+				return nil, fmt.Errorf("line %d: %v", line, err)
+			}
+		}
+		records = append(records, DistanceRecord{
+			Key1:            fields[0],
+			Key2:            fields[1],
+			RawDistance:     raw,
+			ReducedDistance: reduced,
+			Failed:          failed,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return NewDistanceMatrix(records)
+}
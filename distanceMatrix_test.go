@@ -0,0 +1,160 @@
+package reductions
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+var matrixRecords = []DistanceRecord{
+	{Key1: "seq3", Key2: "seq1", RawDistance: 0.5, ReducedDistance: 0.25},
+	{Key1: "seq1", Key2: "seq2", RawDistance: 0.1, ReducedDistance: 0.2},
+	{Key1: "seq2", Key2: "seq3", RawDistance: 0.75, ReducedDistance: 0.125},
+}
+
+func TestNewDistanceMatrix(t *testing.T) {
+	matrix, err := NewDistanceMatrix(matrixRecords)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	wantedKeys := []string{"seq1", "seq2", "seq3"}
+	for i, key := range wantedKeys {
+		if matrix.Keys[i] != key {
+			t.Errorf("wanted keys %v, got %v", wantedKeys, matrix.Keys)
+		}
+	}
+
+	raw, reduced, ok := matrix.Get("seq1", "seq3")
+	if !ok || raw != 0.5 || reduced != 0.25 {
+		t.Errorf("wrong distances for seq1, seq3: %v %v %v", raw, reduced, ok)
+	}
+
+	records := matrix.Records()
+	if !AreDistanceRecordSlicesEqual(records, matrixRecords) {
+		t.Errorf("wanted %v got %v", matrixRecords, records)
+	}
+	if records[0].Key1 != "seq1" || records[0].Key2 != "seq2" || records[2].Key1 != "seq2" {
+		t.Errorf("records are not in stable order: %v", records)
+	}
+}
+
+func TestNewDistanceMatrixMissingAndConflicts(t *testing.T) {
+	matrix, err := NewDistanceMatrix(matrixRecords[:2])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if raw, _, _ := matrix.Get("seq2", "seq3"); !math.IsNaN(raw) {
+		t.Errorf("missing pair should be NaN, got %v", raw)
+	}
+	if len(matrix.Records()) != 2 {
+		t.Errorf("missing pairs should not be returned as records")
+	}
+
+	conflicting := append([]DistanceRecord{}, matrixRecords...)
+	conflicting = append(conflicting, DistanceRecord{Key1: "seq1", Key2: "seq3", RawDistance: 0.9})
+	if _, err := NewDistanceMatrix(conflicting); err == nil {
+		t.Errorf("conflicting records should return an error")
+	}
+}
+
+func TestDistanceMatrix_WritePhylip(t *testing.T) {
+	matrix, _ := NewDistanceMatrix(matrixRecords)
+	var buf bytes.Buffer
+	if err := matrix.WritePhylip(&buf, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wanted := "3\n" +
+		"seq1 0.000000 0.100000 0.500000\n" +
+		"seq2 0.100000 0.000000 0.750000\n" +
+		"seq3 0.500000 0.750000 0.000000\n"
+	if buf.String() != wanted {
+		t.Errorf("Wanted:\n%s\nGot:\n%s", wanted, buf.String())
+	}
+}
+
+func TestDistanceMatrix_WriteSquareTSV(t *testing.T) {
+	matrix, _ := NewDistanceMatrix(matrixRecords)
+	var buf bytes.Buffer
+	if err := matrix.WriteSquareTSV(&buf, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wanted := "\tseq1\tseq2\tseq3\n" +
+		"seq1\t0\t0.2\t0.25\n" +
+		"seq2\t0.2\t0\t0.125\n" +
+		"seq3\t0.25\t0.125\t0\n"
+	if buf.String() != wanted {
+		t.Errorf("Wanted:\n%s\nGot:\n%s", wanted, buf.String())
+	}
+}
+
+func TestDistanceMatrix_LongTSVRoundTrip(t *testing.T) {
+	records := append([]DistanceRecord{}, matrixRecords...)
+	records[0].RawDistance = 1. / 3.
+	matrix, _ := NewDistanceMatrix(records)
+
+	var buf bytes.Buffer
+	if err := matrix.WriteLongTSV(&buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	loaded, err := ReadLongTSV(&buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !AreDistanceRecordSlicesEqual(loaded.Records(), records) {
+		t.Errorf("wanted %v got %v", records, loaded.Records())
+	}
+}
+
+func TestDistanceMatrixFailedRecords(t *testing.T) {
+	failed := DistanceRecord{Key1: "seq1", Key2: "seq3", RawDistance: math.NaN(), ReducedDistance: math.NaN(), Failed: true}
+	records := append([]DistanceRecord{}, matrixRecords[1:]...)
+	records = append(records, failed, failed)
+	matrix, err := NewDistanceMatrix(records)
+	if err != nil {
+		t.Fatalf("identical failed records should not conflict: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := matrix.WriteLongTSV(&buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	loaded, err := ReadLongTSV(&buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, m := range []*DistanceMatrix{matrix, loaded} {
+		got := m.Records()
+		if len(got) != 3 || !got[1].Failed || !math.IsNaN(got[1].RawDistance) || got[0].Failed || got[2].Failed {
+			t.Errorf("failed record was not kept: %v", got)
+		}
+	}
+
+	unflagged := failed
+	unflagged.Failed = false
+	if _, err := NewDistanceMatrix([]DistanceRecord{failed, unflagged}); err == nil {
+		t.Errorf("records with different Failed flags should conflict")
+	}
+
+	legacy := "key1\tkey2\traw\treduced\ns1\ts2\t0.1\t0.2\n"
+	if matrix, err := ReadLongTSV(bytes.NewBufferString(legacy)); err != nil || len(matrix.Records()) != 1 || matrix.Records()[0].Failed {
+		t.Errorf("unexpected result for a file without failed column: %v", err)
+	}
+}
+
+func TestReadLongTSVErrors(t *testing.T) {
+	tests := []struct {
+		name, input string
+	}{
+		{name: "MissingField", input: "key1\tkey2\traw\treduced\ns1\ts2\t0.1\n"},
+		{name: "NotANumber", input: "s1\ts2\tabc\t0.1\n"},
+		{name: "NotABool", input: "s1\ts2\t0.1\t0.1\tmaybe\n"},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			if _, err := ReadLongTSV(bytes.NewBufferString(testCase.input)); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
@@ -1,34 +1,106 @@
 package reductions
 
 import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
 	"testing"
 )
 
+// randomSequence returns a reproducible random nucleotide sequence
+func randomSequence(rng *rand.Rand, length int) string {
+	bases := []byte("ACGT")
+	seq := make([]byte, length)
+	for i := range seq {
+		seq[i] = bases[rng.Intn(len(bases))]
+	}
+	return string(seq)
+}
+
+// mutateSequence returns a copy of seq where a fraction of positions are substituted
+func mutateSequence(rng *rand.Rand, seq string, rate float64) string {
+	bases := []byte("ACGT")
+	mutated := []byte(seq)
+	for i := range mutated {
+		if rng.Float64() < rate {
+			mutated[i] = bases[rng.Intn(len(bases))]
+		}
+	}
+	return string(mutated)
+}
+
+func benchmarkKmerSets(b *testing.B) (StringSet, StringSet) {
+	rng := rand.New(rand.NewSource(42))
+	seq := randomSequence(rng, 10000)
+	kmers1, err := Kmerize(seq, 15)
+	if err != nil {
+		b.Fatal(err)
+	}
+	kmers2, err := Kmerize(mutateSequence(rng, seq, 0.05), 15)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return kmers1, kmers2
+}
+
+// BenchmarkJaccardSimilarityMaterialized is the baseline building the
+// intersection and union sets for each pair
+func BenchmarkJaccardSimilarityMaterialized(b *testing.B) {
+	kmers1, kmers2 := benchmarkKmerSets(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = float64(len(kmers1.Intersection(kmers2))) / float64(len(kmers1.Union(kmers2)))
+	}
+}
+
+func BenchmarkJaccardSimilarity(b *testing.B) {
+	kmers1, kmers2 := benchmarkKmerSets(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		JaccardSimilarity(kmers1, kmers2)
+	}
+}
+
+func BenchmarkJaccardSimilarityUint64(b *testing.B) {
+	rng := rand.New(rand.NewSource(42))
+	seq := randomSequence(rng, 10000)
+	kmers1, _ := KmerizeEncoded(seq, 15)
+	kmers2, _ := KmerizeEncoded(mutateSequence(rng, seq, 0.05), 15)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		JaccardSimilarityUint64(kmers1, kmers2)
+	}
+}
+
 func BenchmarkGetDistancesIdentity(b *testing.B) {
 	sequences, _, _ := ParseFasta("test_data/seqs.fasta")
 	for i := 0; i < b.N; i++ {
-		GetDistances(sequences, 5, Identity)
+		GetDistances(sequences, 5, Identity, FailOnError)
 	}
 }
 
 func BenchmarkGetDistancesHomopolymerCompression(b *testing.B) {
 	sequences, _, _ := ParseFasta("test_data/seqs.fasta")
 	for i := 0; i < b.N; i++ {
-		GetDistances(sequences, 5, HomopolymerCompression)
+		GetDistances(sequences, 5, HomopolymerCompression, FailOnError)
 	}
 }
 
 func BenchmarkGetDistancesMultithreadIdentity(b *testing.B) {
 	sequences, _, _ := ParseFasta("test_data/seqs.fasta")
 	for i := 0; i < b.N; i++ {
-		GetDistancesMultiThread(sequences, 5, Identity, 4)
+		GetDistancesMultiThread(context.Background(), sequences, 5, Identity, 4, FailOnError)
 	}
 }
 
 func BenchmarkGetDistancesMultithreadHomopolymerCompression(b *testing.B) {
 	sequences, _, _ := ParseFasta("test_data/seqs.fasta")
 	for i := 0; i < b.N; i++ {
-		GetDistancesMultiThread(sequences, 5, HomopolymerCompression, 4)
+		GetDistancesMultiThread(context.Background(), sequences, 5, HomopolymerCompression, 4, FailOnError)
 	}
 }
 
@@ -125,28 +197,46 @@ func TestGetDistancesMultiThread(t *testing.T) {
 		"seq3": "GTCAGGCATA",
 		"seq4": "CGATGGCATA",
 	}
+	identityWanted := []DistanceRecord{
+		{Key1: "seq2", Key2: "seq3", RawDistance: 0.33333333333333337, ReducedDistance: 0.33333333333333337},
+		{Key1: "seq2", Key2: "seq4", RawDistance: 0.8333333333333334, ReducedDistance: 0.8333333333333334},
+		{Key1: "seq3", Key2: "seq4", RawDistance: 0.6363636363636364, ReducedDistance: 0.6363636363636364},
+		{Key1: "seq1", Key2: "seq2", RawDistance: 0.8181818181818181, ReducedDistance: 0.8181818181818181},
+		{Key1: "seq1", Key2: "seq3", RawDistance: 0.7272727272727273, ReducedDistance: 0.7272727272727273},
+		{Key1: "seq1", Key2: "seq4", RawDistance: 0.7, ReducedDistance: 0.7},
+	}
 	tests := []struct {
 		name      string
 		wanted    []DistanceRecord
 		reduction func(string) string
+		threads   int
 	}{
 		{
-			name: "Identity",
-			wanted: []DistanceRecord{
-				{Key1: "seq2", Key2: "seq3", RawDistance: 0.33333333333333337, ReducedDistance: 0.33333333333333337},
-				{Key1: "seq2", Key2: "seq4", RawDistance: 0.8333333333333334, ReducedDistance: 0.8333333333333334},
-				{Key1: "seq3", Key2: "seq4", RawDistance: 0.6363636363636364, ReducedDistance: 0.6363636363636364},
-				{Key1: "seq1", Key2: "seq2", RawDistance: 0.8181818181818181, ReducedDistance: 0.8181818181818181},
-				{Key1: "seq1", Key2: "seq3", RawDistance: 0.7272727272727273, ReducedDistance: 0.7272727272727273},
-				{Key1: "seq1", Key2: "seq4", RawDistance: 0.7, ReducedDistance: 0.7},
-			},
+			name:      "Identity",
+			wanted:    identityWanted,
+			reduction: Identity,
+			threads:   8,
+		},
+		{
+			name:      "SingleThread",
+			wanted:    identityWanted,
 			reduction: Identity,
+			threads:   1,
+		},
+		{
+			name:      "NoThreads",
+			wanted:    identityWanted,
+			reduction: Identity,
+			threads:   0,
 		},
 	}
 
 	for _, testCase := range tests {
 		t.Run(testCase.name, func(t *testing.T) {
-			distances := GetDistancesMultiThread(seqs, 3, testCase.reduction, 8)
+			distances, _, err := GetDistancesMultiThread(context.Background(), seqs, 3, testCase.reduction, testCase.threads, FailOnError)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
 			if !AreDistanceRecordSlicesEqual(testCase.wanted, distances) {
 				t.Errorf("Wanted %v got %v\n(wanted length %v, got %v)", testCase.wanted, distances, len(testCase.wanted), len(distances))
 			}
@@ -154,6 +244,31 @@ func TestGetDistancesMultiThread(t *testing.T) {
 	}
 }
 
+func TestGetDistancesMultiThreadErrors(t *testing.T) {
+	seqs := map[string]string{
+		"seq1": "ATTGCATCAT",
+		"seq2": "AGTCAGGCAG",
+		"seq3": "GT",
+	}
+
+	_, _, err := GetDistancesMultiThread(context.Background(), seqs, 3, Identity, 2, FailOnError)
+	if !errors.Is(err, ErrReadTooShort) {
+		t.Errorf("expected a short read error for a sequence shorter than k, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err = GetDistancesMultiThread(ctx, map[string]string{"seq1": "ATTGCATCAT", "seq2": "AGTCAGGCAG"}, 3, Identity, 2, FailOnError)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+
+	distances, _, err := GetDistancesMultiThread(context.Background(), map[string]string{"seq1": "ATTGCATCAT"}, 3, Identity, 2, FailOnError)
+	if err != nil || len(distances) != 0 {
+		t.Errorf("expected no distances and no error for a single sequence, got %v, %v", distances, err)
+	}
+}
+
 func TestGetDistances(t *testing.T) {
 
 	seqs := map[string]string{
@@ -183,7 +298,10 @@ func TestGetDistances(t *testing.T) {
 
 	for _, testCase := range tests {
 		t.Run(testCase.name, func(t *testing.T) {
-			distances := GetDistances(seqs, 3, testCase.reduction)
+			distances, _, err := GetDistances(seqs, 3, testCase.reduction, FailOnError)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
 			if !AreDistanceRecordSlicesEqual(testCase.wanted, distances) {
 				t.Errorf("Wanted %v got %v\n(wanted length %v, got %v)", testCase.wanted, distances, len(testCase.wanted), len(distances))
 			}
@@ -191,6 +309,45 @@ func TestGetDistances(t *testing.T) {
 	}
 }
 
+func TestGetDistancesErrorPolicies(t *testing.T) {
+	seqs := map[string]string{
+		"seq1": "ATTGCATCAT",
+		"seq2": "AGTCAGGCAG",
+		"seq3": "AAAAAAAAAA",
+	}
+
+	_, summary, err := GetDistances(seqs, 3, HomopolymerCompression, FailOnError)
+	var shortErr *ShortReadError
+	if !errors.As(err, &shortErr) || shortErr.Key != "seq3" || !shortErr.Reduced {
+		t.Errorf("expected a reduced short read error for seq3, got %v", err)
+	}
+	if len(summary.ShortReducedReads) != 1 || summary.ShortReducedReads[0] != "seq3" {
+		t.Errorf("expected seq3 to be reported as a short reduced read, got %v", summary.ShortReducedReads)
+	}
+
+	distances, summary, err := GetDistances(seqs, 3, HomopolymerCompression, SkipOnError)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(distances) != 1 || summary.Skipped != 2 || summary.Pairs != 3 {
+		t.Errorf("expected 1 record and 2 skipped pairs, got %v, %v", distances, summary)
+	}
+
+	distances, summary, err = GetDistances(seqs, 3, HomopolymerCompression, FlagOnError)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	valid, failed := FilterFailedRecords(distances)
+	if len(valid) != 1 || len(failed) != 2 || summary.Flagged != 2 {
+		t.Errorf("expected 1 valid and 2 flagged records, got %v, %v", distances, summary)
+	}
+	for _, record := range failed {
+		if math.IsNaN(record.RawDistance) || !math.IsNaN(record.ReducedDistance) {
+			t.Errorf("flagged record should have a raw distance and a NaN reduced distance: %v", record)
+		}
+	}
+}
+
 func TestDistanceRecord_IsEqual(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -232,6 +389,48 @@ func TestDistanceRecord_IsEqual(t *testing.T) {
 	}
 }
 
+func TestAreDistanceRecordSlicesEqual(t *testing.T) {
+	tied := []DistanceRecord{
+		{Key1: "k1", Key2: "k2", RawDistance: 0.5, ReducedDistance: 0.1},
+		{Key1: "k3", Key2: "k4", RawDistance: 0.5, ReducedDistance: 0.2},
+		{Key1: "k5", Key2: "k6", RawDistance: 0.1, ReducedDistance: 0.3},
+	}
+	tests := []struct {
+		name   string
+		a, b   []DistanceRecord
+		wanted bool
+	}{
+		{name: "SameOrder", a: tied, b: tied, wanted: true},
+		{
+			name:   "TiedDistancesInOtherOrder",
+			a:      tied,
+			b:      []DistanceRecord{tied[1], tied[2], tied[0]},
+			wanted: true,
+		},
+		{
+			name:   "TiedDistancesSwitchedKeys",
+			a:      tied,
+			b:      []DistanceRecord{{Key1: "k4", Key2: "k3", RawDistance: 0.5, ReducedDistance: 0.2}, tied[0], tied[2]},
+			wanted: true,
+		},
+		{name: "DifferentLengths", a: tied, b: tied[:2], wanted: false},
+		{
+			name:   "DifferentReducedDistance",
+			a:      tied,
+			b:      []DistanceRecord{tied[1], {Key1: "k1", Key2: "k2", RawDistance: 0.5, ReducedDistance: 0.9}, tied[2]},
+			wanted: false,
+		},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			if ans := AreDistanceRecordSlicesEqual(testCase.a, testCase.b); ans != testCase.wanted {
+				t.Errorf("got equal=%v for slices %v and %v", ans, testCase.a, testCase.b)
+			}
+		})
+	}
+}
+
 func TestMakeWFASequenceSets(t *testing.T) {
 	distances := []DistanceRecord{
 		{Key1: "key1", Key2: "key1_err"},
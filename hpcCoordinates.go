@@ -0,0 +1,144 @@
+package reductions
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hillbig/rsdic"
+	"strings"
+)
+
+// HPCCoordinates links positions of a raw read to positions of its homopolymer
+// compressed read. It is backed by a bit vector with one bit per raw position, set at
+// the start of each homopolymer run, so that compressed position i is the i-th run.
+type HPCCoordinates struct {
+	runStarts *rsdic.RSDic
+}
+
+// HomopolymerCompressionWithRuns returns the homopolymer compressed version of a read
+// and the length of each run, so that the read can be decompressed
+func HomopolymerCompressionWithRuns(read string) (string, []int) {
+	var builder strings.Builder
+	runs := make([]int, 0)
+	for i := 0; i < len(read); i++ {
+		if i > 0 && read[i] == read[i-1] {
+			runs[len(runs)-1]++
+			continue
+		}
+		builder.WriteByte(read[i])
+		runs = append(runs, 1)
+	}
+	return builder.String(), runs
+}
+
+// HomopolymerCompressionWithCoordinates returns the homopolymer compressed version of
+// a read and the coordinates linking it to the read
+func HomopolymerCompressionWithCoordinates(read string) (string, *HPCCoordinates) {
+	runStarts := rsdic.New()
+	var builder strings.Builder
+	for i := 0; i < len(read); i++ {
+		start := i == 0 || read[i] != read[i-1]
+		runStarts.PushBack(start)
+		if start {
+			builder.WriteByte(read[i])
+		}
+	}
+	return builder.String(), &HPCCoordinates{runStarts: runStarts}
+}
+
+// NewHPCCoordinates makes coordinates from the run lengths returned by
+// HomopolymerCompressionWithRuns
+func NewHPCCoordinates(runs []int) (*HPCCoordinates, error) {
+	runStarts := rsdic.New()
+	for i, run := range runs {
+		if run <= 0 {
+			return nil, fmt.Errorf("run %d has length %d", i, run)
+		}
+		runStarts.PushBack(true)
+		for j := 1; j < run; j++ {
+			runStarts.PushBack(false)
+		}
+	}
+	return &HPCCoordinates{runStarts: runStarts}, nil
+}
+
+// HomopolymerDecompression restores a homopolymer compressed read from its run lengths
+func HomopolymerDecompression(compressed string, runs []int) (string, error) {
+	if len(compressed) != len(runs) {
+		return "", fmt.Errorf("%d run lengths for a compressed read of length %d", len(runs), len(compressed))
+	}
+	var builder strings.Builder
+	for i, run := range runs {
+		if run <= 0 {
+			return "", fmt.Errorf("run %d has length %d", i, run)
+		}
+		for j := 0; j < run; j++ {
+			builder.WriteByte(compressed[i])
+		}
+	}
+	return builder.String(), nil
+}
+
+// RawLength returns the length of the uncompressed read
+func (c *HPCCoordinates) RawLength() int {
+	return int(c.runStarts.Num())
+}
+
+// ReducedLength returns the length of the compressed read
+func (c *HPCCoordinates) ReducedLength() int {
+	return int(c.runStarts.OneNum())
+}
+
+// RunLengths returns the length of each run
+func (c *HPCCoordinates) RunLengths() []int {
+	runs := make([]int, c.ReducedLength())
+	for i := range runs {
+		runs[i] = int(c.runStarts.Select(uint64(i+1), true) - c.runStarts.Select(uint64(i), true))
+	}
+	return runs
+}
+
+// Decompress restores the uncompressed read from its compressed version
+func (c *HPCCoordinates) Decompress(compressed string) (string, error) {
+	if len(compressed) != c.ReducedLength() {
+		return "", errors.New("the compressed read does not match the coordinates")
+	}
+	return HomopolymerDecompression(compressed, c.RunLengths())
+}
+
+// RawToReduced returns the compressed position of the run containing a raw position,
+// and whether the raw position is the start of its run
+func (c *HPCCoordinates) RawToReduced(pos int) (int, bool, error) {
+	if pos < 0 || pos >= c.RawLength() {
+		return 0, false, fmt.Errorf("raw position %d of a read of length %d: %w", pos, c.RawLength(), ErrPositionOutOfRange)
+	}
+	return int(c.runStarts.Rank(uint64(pos+1), true)) - 1, c.runStarts.Bit(uint64(pos)), nil
+}
+
+// ReducedToRaw returns the raw position of the start of a run
+func (c *HPCCoordinates) ReducedToRaw(pos int) (int, error) {
+	if pos < 0 || pos >= c.ReducedLength() {
+		return 0, fmt.Errorf("reduced position %d of a read of length %d: %w", pos, c.ReducedLength(), ErrPositionOutOfRange)
+	}
+	return int(c.runStarts.Select(uint64(pos), true)), nil
+}
+
+// RawIntervalToReduced lifts the half-open raw interval [start, end) to the runs it overlaps
+func (c *HPCCoordinates) RawIntervalToReduced(start, end int) (int, int, error) {
+	if start < 0 || start > end || end > c.RawLength() {
+		return 0, 0, fmt.Errorf("raw interval [%d, %d) of a read of length %d: %w", start, end, c.RawLength(), ErrPositionOutOfRange)
+	}
+	if start == end {
+		rank := int(c.runStarts.Rank(uint64(start), true))
+		return rank, rank, nil
+	}
+	return int(c.runStarts.Rank(uint64(start+1), true)) - 1, int(c.runStarts.Rank(uint64(end), true)), nil
+}
+
+// ReducedIntervalToRaw lifts the half-open compressed interval [start, end) to the
+// raw interval covering its runs
+func (c *HPCCoordinates) ReducedIntervalToRaw(start, end int) (int, int, error) {
+	if start < 0 || start > end || end > c.ReducedLength() {
+		return 0, 0, fmt.Errorf("reduced interval [%d, %d) of a read of length %d: %w", start, end, c.ReducedLength(), ErrPositionOutOfRange)
+	}
+	return int(c.runStarts.Select(uint64(start), true)), int(c.runStarts.Select(uint64(end), true)), nil
+}
@@ -0,0 +1,126 @@
+package reductions
+
+import (
+	"errors"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestHomopolymerCompressionWithRuns(t *testing.T) {
+	var tests = []struct {
+		name, read, wanted string
+		runs               []int
+	}{
+		{name: "Empty", read: "", wanted: "", runs: []int{}},
+		{name: "Single", read: "A", wanted: "A", runs: []int{1}},
+		{name: "Runs", read: "AAACGGGGTA", wanted: "ACGTA", runs: []int{3, 1, 4, 1, 1}},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			compressed, runs := HomopolymerCompressionWithRuns(testCase.read)
+			if compressed != testCase.wanted || !reflect.DeepEqual(runs, testCase.runs) {
+				t.Errorf("%s, %v (got)\n%s, %v (wanted)", compressed, runs, testCase.wanted, testCase.runs)
+			}
+			if compressed != HomopolymerCompression(testCase.read) {
+				t.Errorf("%s (got)\n%s (wanted)", compressed, HomopolymerCompression(testCase.read))
+			}
+			decompressed, err := HomopolymerDecompression(compressed, runs)
+			if err != nil || decompressed != testCase.read {
+				t.Errorf("%s, %v (got)\n%s (wanted)", decompressed, err, testCase.read)
+			}
+
+			_, coordinates := HomopolymerCompressionWithCoordinates(testCase.read)
+			fromRuns, err := NewHPCCoordinates(runs)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, c := range []*HPCCoordinates{coordinates, fromRuns} {
+				if got := c.RunLengths(); !reflect.DeepEqual(got, testCase.runs) {
+					t.Errorf("%v (got)\n%v (wanted)", got, testCase.runs)
+				}
+				decompressed, err := c.Decompress(compressed)
+				if err != nil || decompressed != testCase.read {
+					t.Errorf("%s, %v (got)\n%s (wanted)", decompressed, err, testCase.read)
+				}
+			}
+		})
+	}
+}
+
+func TestHPCCoordinatesLifting(t *testing.T) {
+	rng := rand.New(rand.NewSource(5))
+	read := randomSequence(rng, 200)
+	compressed, c := HomopolymerCompressionWithCoordinates(read)
+	if c.RawLength() != len(read) || c.ReducedLength() != len(compressed) {
+		t.Fatalf("%d, %d (got lengths)\n%d, %d (wanted)", c.RawLength(), c.ReducedLength(), len(read), len(compressed))
+	}
+	for i := 0; i < len(read); i++ {
+		pos, start, err := c.RawToReduced(i)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if compressed[pos] != read[i] {
+			t.Errorf("raw %d lifted to %d: %c != %c", i, pos, read[i], compressed[pos])
+		}
+		if start != (i == 0 || read[i] != read[i-1]) {
+			t.Errorf("raw %d: wrong run start %v", i, start)
+		}
+	}
+
+	// raw:        0 1 2 3 4 5 6 7 8 9
+	// read:       A A A C G G G G T A
+	// compressed: 0     1 2       3 4
+	_, c = HomopolymerCompressionWithCoordinates("AAACGGGGTA")
+	var tests = []struct {
+		name               string
+		reduced            bool
+		start, end         int
+		wantStart, wantEnd int
+	}{
+		{name: "RawAll", start: 0, end: 10, wantStart: 0, wantEnd: 5},
+		{name: "RawInsideRun", start: 5, end: 7, wantStart: 2, wantEnd: 3},
+		{name: "RawAcrossRuns", start: 2, end: 5, wantStart: 0, wantEnd: 3},
+		{name: "RawEmpty", start: 3, end: 3, wantStart: 1, wantEnd: 1},
+		{name: "ReducedAll", reduced: true, start: 0, end: 5, wantStart: 0, wantEnd: 10},
+		{name: "ReducedRun", reduced: true, start: 2, end: 3, wantStart: 4, wantEnd: 8},
+		{name: "ReducedEmpty", reduced: true, start: 1, end: 1, wantStart: 3, wantEnd: 3},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			lift := c.RawIntervalToReduced
+			if testCase.reduced {
+				lift = c.ReducedIntervalToRaw
+			}
+			start, end, err := lift(testCase.start, testCase.end)
+			if err != nil || start != testCase.wantStart || end != testCase.wantEnd {
+				t.Errorf("[%d, %d), %v (got)\n[%d, %d) (wanted)", start, end, err, testCase.wantStart, testCase.wantEnd)
+			}
+		})
+	}
+	if raw, err := c.ReducedToRaw(3); err != nil || raw != 8 {
+		t.Errorf("%d, %v (got)\n8 (wanted)", raw, err)
+	}
+}
+
+func TestHPCCoordinatesErrors(t *testing.T) {
+	_, c := HomopolymerCompressionWithCoordinates("AACG")
+	if _, _, err := c.RawToReduced(4); !errors.Is(err, ErrPositionOutOfRange) {
+		t.Errorf("Was expecting ErrPositionOutOfRange, got %v", err)
+	}
+	if _, err := c.ReducedToRaw(-1); !errors.Is(err, ErrPositionOutOfRange) {
+		t.Errorf("Was expecting ErrPositionOutOfRange, got %v", err)
+	}
+	if _, _, err := c.ReducedIntervalToRaw(1, 4); !errors.Is(err, ErrPositionOutOfRange) {
+		t.Errorf("Was expecting ErrPositionOutOfRange, got %v", err)
+	}
+	if _, err := c.Decompress("AC"); err == nil {
+		t.Error("Was expecting error when decompressing a read of the wrong length")
+	}
+	if _, err := NewHPCCoordinates([]int{1, 0}); err == nil {
+		t.Error("Was expecting error for an empty run")
+	}
+	if _, err := HomopolymerDecompression("AC", []int{1}); err == nil {
+		t.Error("Was expecting error for missing run lengths")
+	}
+}
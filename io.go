@@ -2,10 +2,8 @@ package reductions
 
 import (
 	"bufio"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"os"
 	"strings"
 )
@@ -150,15 +148,13 @@ func printSequences(seqs map[string]string) {
 	}
 }
 
-// CheckSurjectionFile unmarshalls a reduction function .json to a map
+// CheckSurjectionFile unmarshalls a reduction function .json to a map. Both the
+// versioned format and the bare mapping are accepted, see ReadSurjectionFile.
 func CheckSurjectionFile(path string, output *map[string]string) error {
-	file, err := ioutil.ReadFile(path)
-	if err != nil {
-		return err
-	}
-	err = json.Unmarshal(file, output)
+	file, err := ReadSurjectionFile(path)
 	if err != nil {
 		return err
 	}
+	*output = file.Mapping
 	return nil
 }
@@ -0,0 +1,258 @@
+package reductions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+)
+
+// KResult holds the distances computed for one value of k, and the objective
+// scores when evaluated with EvaluateMultiK. ObjectiveErr is set if the
+// objectives could not be evaluated for that k.
+type KResult struct {
+	K            int
+	Distances    []DistanceRecord
+	Summary      DistanceSummary
+	Scores       []ObjectiveScore
+	ObjectiveErr error
+}
+
+// kmerEntry holds the raw and reduced k-mer sets of a sequence for one k,
+// or the errors that prevented computing them
+type kmerEntry struct {
+	raw, reduced       StringSet
+	rawErr, reducedErr error
+}
+
+// indexPairJob holds the indices of a pair of sequences to compare
+type indexPairJob struct {
+	i, j int
+}
+
+// multiKResult holds the records of a pair of sequences for every k
+type multiKResult struct {
+	records []DistanceRecord
+	errs    []error
+}
+
+// parallelFor calls body for all integers in [0, n) using threads goroutines,
+// stopping early if the context is cancelled
+func parallelFor(ctx context.Context, n, threads int, body func(i int)) error {
+	jobs := make(chan int, threads)
+	go func() {
+		defer close(jobs)
+		for i := 0; i < n; i++ {
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for w := 0; w < threads; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if ctx.Err() != nil {
+					return
+				}
+				body(i)
+			}
+		}()
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+// kmerizeChecked returns the k-mer set of a sequence, or a ShortReadError if
+// it is shorter than k
+func kmerizeChecked(key, seq string, k int, reduced bool) (StringSet, error) {
+	if len(seq) < k {
+		return nil, &ShortReadError{Key: key, Length: len(seq), K: k, Reduced: reduced}
+	}
+	return Kmerize(seq, k)
+}
+
+// kmerizeEntry computes the k-mer sets of a sequence before and after reduction
+func kmerizeEntry(key, raw, reduced string, k int) kmerEntry {
+	entry := kmerEntry{}
+	entry.raw, entry.rawErr = kmerizeChecked(key, raw, k, false)
+	entry.reduced, entry.reducedErr = kmerizeChecked(key, reduced, k, true)
+	return entry
+}
+
+// kmerizeAll computes the k-mer sets of all sequences for all k, using threads goroutines.
+// entries[kIndex][keyIndex] holds the sets of sequence seqKeys[keyIndex] for ks[kIndex].
+func kmerizeAll(ctx context.Context, seqKeys []string, seqRecords, reducedRecords map[string]string, ks []int, threads int) ([][]kmerEntry, error) {
+	entries := make([][]kmerEntry, len(ks))
+	for i := range entries {
+		entries[i] = make([]kmerEntry, len(seqKeys))
+	}
+
+	err := parallelFor(ctx, len(seqKeys), threads, func(i int) {
+		key := seqKeys[i]
+		for kIndex, k := range ks {
+			entries[kIndex][i] = kmerizeEntry(key, seqRecords[key], reducedRecords[key], k)
+		}
+	})
+
+	return entries, err
+}
+
+// entryDistance computes the Jaccard distance between 2 k-mer sets
+func entryDistance(set1, set2 StringSet, err1, err2 error) (float64, error) {
+	if err1 != nil {
+		return math.NaN(), err1
+	}
+	if err2 != nil {
+		return math.NaN(), err2
+	}
+	return 1. - JaccardSimilarity(set1, set2), nil
+}
+
+// GetDistancesMultiK computes distances between all pairs of sequences for several
+// values of k. Each sequence is reduced once and its k-mer sets are computed once
+// per k, then reused for all the pairs it is part of. This trades memory (all
+// k-mer sets are kept) for not recomputing them for every pair.
+// Errors are handled according to policy separately for each k, like GetDistancesMultiThread.
+func GetDistancesMultiK(ctx context.Context, seqRecords map[string]string, ks []int, reduction func(string) string, threads int, policy DistanceErrorPolicy) ([]KResult, error) {
+	if len(ks) == 0 {
+		return nil, errors.New("at least one value of k is needed")
+	}
+	if threads < 1 {
+		threads = 1
+	}
+
+	seqKeys := make([]string, 0, len(seqRecords))
+	for key := range seqRecords {
+		seqKeys = append(seqKeys, key)
+	}
+	sort.Strings(seqKeys)
+
+	workerCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	reducedRecords := reduceSequences(seqRecords, reduction)
+	results := make([]KResult, len(ks))
+	for i, k := range ks {
+		results[i] = KResult{
+			K:         k,
+			Distances: []DistanceRecord{},
+			Summary:   shortReadsSummary(seqRecords, reducedRecords, k),
+		}
+	}
+
+	entries, err := kmerizeAll(workerCtx, seqKeys, seqRecords, reducedRecords, ks, threads)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make(chan indexPairJob, threads)
+	pairResults := make(chan multiKResult, threads)
+	go func() {
+		defer close(jobs)
+		for i := range seqKeys {
+			for j := i + 1; j < len(seqKeys); j++ {
+				select {
+				case jobs <- indexPairJob{i: i, j: j}:
+				case <-workerCtx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for w := 0; w < threads; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				if workerCtx.Err() != nil {
+					return
+				}
+				result := multiKResult{records: make([]DistanceRecord, len(ks)), errs: make([]error, len(ks))}
+				for kIndex := range ks {
+					e1, e2 := entries[kIndex][job.i], entries[kIndex][job.j]
+					record := DistanceRecord{Key1: seqKeys[job.i], Key2: seqKeys[job.j], ReducedDistance: math.NaN()}
+					var err error
+					record.RawDistance, err = entryDistance(e1.raw, e2.raw, e1.rawErr, e2.rawErr)
+					if err != nil {
+						err = fmt.Errorf("raw distance between %s and %s: %w", record.Key1, record.Key2, err)
+					} else {
+						record.ReducedDistance, err = entryDistance(e1.reduced, e2.reduced, e1.reducedErr, e2.reducedErr)
+						if err != nil {
+							err = fmt.Errorf("reduced distance between %s and %s: %w", record.Key1, record.Key2, err)
+						}
+					}
+					result.records[kIndex], result.errs[kIndex] = record, err
+				}
+				select {
+				case pairResults <- result:
+				case <-workerCtx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(pairResults)
+	}()
+
+	var firstErr error
+	for result := range pairResults {
+		for kIndex := range ks {
+			kResult := &results[kIndex]
+			record, err := result.records[kIndex], result.errs[kIndex]
+			kResult.Summary.Pairs++
+			if err != nil {
+				switch policy {
+				case SkipOnError:
+					kResult.Summary.Skipped++
+					continue
+				case FlagOnError:
+					kResult.Summary.Flagged++
+					record.Failed = true
+				default:
+					if firstErr == nil {
+						firstErr = fmt.Errorf("k=%d: %w", ks[kIndex], err)
+						cancel()
+					}
+					continue
+				}
+			}
+			kResult.Distances = append(kResult.Distances, record)
+		}
+	}
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// EvaluateMultiK computes the distances for several values of k with
+// GetDistancesMultiK, splits them into close and far sets and evaluates the
+// objectives for each k. Failed records are left out of the evaluation.
+func EvaluateMultiK(ctx context.Context, seqRecords map[string]string, ks []int, reduction func(string) string, threads int, policy DistanceErrorPolicy, split SetSplitter, objectives []Objective) ([]KResult, error) {
+	results, err := GetDistancesMultiK(ctx, seqRecords, ks, reduction, threads, policy)
+	if err != nil {
+		return nil, err
+	}
+	for i := range results {
+		valid, _ := FilterFailedRecords(results[i].Distances)
+		closeSet, farSet := split(valid)
+		results[i].Scores, results[i].ObjectiveErr = EvaluateObjectives(objectives, closeSet, farSet)
+	}
+	return results, nil
+}
@@ -0,0 +1,100 @@
+package reductions
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestGetDistancesMultiK(t *testing.T) {
+	seqs := map[string]string{
+		"seq1": "ATTGCATCAT",
+		"seq2": "AGTCAGGCAG",
+		"seq3": "GTCAGGCATA",
+		"seq4": "CGATGGCATA",
+	}
+	ks := []int{3, 4, 5}
+	results, err := GetDistancesMultiK(context.Background(), seqs, ks, HomopolymerCompression, 3, FailOnError)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != len(ks) {
+		t.Fatalf("wanted %d results got %d", len(ks), len(results))
+	}
+	for i, k := range ks {
+		wanted, _, err := GetDistances(seqs, k, HomopolymerCompression, FailOnError)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if results[i].K != k || !AreDistanceRecordSlicesEqual(results[i].Distances, wanted) {
+			t.Errorf("k=%d: wanted %v got %v", k, wanted, results[i].Distances)
+		}
+		if results[i].Summary.Pairs != 6 {
+			t.Errorf("k=%d: wanted 6 pairs got %v", k, results[i].Summary)
+		}
+	}
+}
+
+func TestGetDistancesMultiKErrors(t *testing.T) {
+	seqs := map[string]string{
+		"seq1": "ATTGCATCAT",
+		"seq2": "AGTCAGGCAG",
+		"seq3": "AATTTGGG",
+	}
+
+	_, err := GetDistancesMultiK(context.Background(), seqs, []int{3, 4}, HomopolymerCompression, 2, FailOnError)
+	if !errors.Is(err, ErrReadTooShort) {
+		t.Errorf("expected a short read error, got %v", err)
+	}
+
+	results, err := GetDistancesMultiK(context.Background(), seqs, []int{3, 4}, HomopolymerCompression, 2, SkipOnError)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results[0].Distances) != 3 || results[0].Summary.Skipped != 0 {
+		t.Errorf("k=3: expected all pairs, got %v", results[0].Summary)
+	}
+	if len(results[1].Distances) != 1 || results[1].Summary.Skipped != 2 {
+		t.Errorf("k=4: expected 2 skipped pairs, got %v", results[1].Summary)
+	}
+	if len(results[1].Summary.ShortReducedReads) != 1 || results[1].Summary.ShortReducedReads[0] != "seq3" {
+		t.Errorf("k=4: expected seq3 to be a short reduced read, got %v", results[1].Summary.ShortReducedReads)
+	}
+
+	if _, err := GetDistancesMultiK(context.Background(), seqs, nil, Identity, 2, FailOnError); err == nil {
+		t.Errorf("expected an error without values of k")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := GetDistancesMultiK(ctx, seqs, []int{3}, Identity, 2, FailOnError); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestEvaluateMultiK(t *testing.T) {
+	seqs := map[string]string{
+		"seq1":     "ATTGCATCATGGACTAGCA",
+		"seq1_err": "ATTGCATCTTGGACTAGCA",
+		"seq2":     "AGTCAGGCAGTTACGATCA",
+		"seq2_err": "AGTCAGGCAGTTACGTTCA",
+	}
+	objectives := []Objective{PhiObjective{}, SeparationMarginObjective{}}
+	results, err := EvaluateMultiK(context.Background(), seqs, []int{3, 5}, Identity, 2, FailOnError, MakeWFASequenceSets, objectives)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, result := range results {
+		if result.ObjectiveErr != nil {
+			t.Errorf("k=%d: unexpected objective error %v", result.K, result.ObjectiveErr)
+			continue
+		}
+		closeSet, farSet := MakeWFASequenceSets(result.Distances)
+		wanted, _ := EvaluateObjectives(objectives, closeSet, farSet)
+		for i := range wanted {
+			if result.Scores[i] != wanted[i] {
+				t.Errorf("k=%d: wanted %v got %v", result.K, wanted, result.Scores)
+			}
+		}
+	}
+}
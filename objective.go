@@ -0,0 +1,219 @@
+package reductions
+
+import (
+	"errors"
+	"fmt"
+	"gonum.org/v1/gonum/stat"
+	"math"
+	"sort"
+)
+
+// Objective evaluates a reduction from the distances of the close and far
+// sequence sets
+type Objective interface {
+	// Name identifies the objective in results
+	Name() string
+	// Evaluate computes the score of the reduction
+	Evaluate(closeSet, farSet []DistanceRecord) (float64, error)
+	// Minimize is true if lower scores are better
+	Minimize() bool
+}
+
+// ObjectiveScore is the score of a reduction for a given objective
+type ObjectiveScore struct {
+	Name  string
+	Value float64
+}
+
+// EvaluateObjectives computes the score of several objectives on the same sequence sets
+func EvaluateObjectives(objectives []Objective, closeSet, farSet []DistanceRecord) ([]ObjectiveScore, error) {
+	scores := make([]ObjectiveScore, len(objectives))
+	for i, objective := range objectives {
+		value, err := objective.Evaluate(closeSet, farSet)
+		if err != nil {
+			return nil, fmt.Errorf("objective %s: %w", objective.Name(), err)
+		}
+		scores[i] = ObjectiveScore{Name: objective.Name(), Value: value}
+	}
+	return scores, nil
+}
+
+// PhiObjective is the C + F/mu objective computed by ObjectivePhiChecked
+type PhiObjective struct{}
+
+// Name implements the Objective interface
+func (PhiObjective) Name() string { return "phi" }
+
+// Minimize implements the Objective interface
+func (PhiObjective) Minimize() bool { return true }
+
+// Evaluate implements the Objective interface
+func (PhiObjective) Evaluate(closeSet, farSet []DistanceRecord) (float64, error) {
+	phi, _, err := ObjectivePhiChecked(closeSet, farSet)
+	return phi.Phi, err
+}
+
+// WeightedPhiObjective is the CloseWeight * C + FarWeight * F/mu variant of ObjectivePhi
+type WeightedPhiObjective struct {
+	CloseWeight, FarWeight float64
+}
+
+// Name implements the Objective interface
+func (o WeightedPhiObjective) Name() string {
+	return fmt.Sprintf("weighted_phi(%g,%g)", o.CloseWeight, o.FarWeight)
+}
+
+// Minimize implements the Objective interface
+func (WeightedPhiObjective) Minimize() bool { return true }
+
+// Evaluate implements the Objective interface
+func (o WeightedPhiObjective) Evaluate(closeSet, farSet []DistanceRecord) (float64, error) {
+	phi, _, err := ObjectivePhiChecked(closeSet, farSet)
+	if err != nil {
+		return 0, err
+	}
+	return o.CloseWeight*phi.C + o.FarWeight*phi.F/phi.Mu, nil
+}
+
+// SpearmanObjective is the Spearman rank correlation between raw and reduced
+// distances over both sequence sets. Failed records are left out.
+type SpearmanObjective struct{}
+
+// Name implements the Objective interface
+func (SpearmanObjective) Name() string { return "spearman" }
+
+// Minimize implements the Objective interface
+func (SpearmanObjective) Minimize() bool { return false }
+
+// Evaluate implements the Objective interface
+func (SpearmanObjective) Evaluate(closeSet, farSet []DistanceRecord) (float64, error) {
+	raw, reduced := splitDistances(closeSet, farSet)
+	if len(raw) < 2 {
+		return 0, errors.New("at least 2 distance records are needed for a correlation")
+	}
+	rawRanks, reducedRanks := rank(raw), rank(reduced)
+	if stat.Variance(rawRanks, nil) == 0 || stat.Variance(reducedRanks, nil) == 0 {
+		return 0, errors.New("correlation is undefined for constant distances")
+	}
+	return stat.Correlation(rawRanks, reducedRanks, nil), nil
+}
+
+// KendallObjective is the Kendall tau-b rank correlation between raw and
+// reduced distances over both sequence sets. It is quadratic in the number of records.
+// Failed records are left out.
+type KendallObjective struct{}
+
+// Name implements the Objective interface
+func (KendallObjective) Name() string { return "kendall" }
+
+// Minimize implements the Objective interface
+func (KendallObjective) Minimize() bool { return false }
+
+// Evaluate implements the Objective interface
+func (KendallObjective) Evaluate(closeSet, farSet []DistanceRecord) (float64, error) {
+	raw, reduced := splitDistances(closeSet, farSet)
+	if len(raw) < 2 {
+		return 0, errors.New("at least 2 distance records are needed for a correlation")
+	}
+
+	var concordant, discordant, tiedRaw, tiedReduced float64
+	for i := range raw {
+		for j := i + 1; j < len(raw); j++ {
+			dRaw, dReduced := raw[i]-raw[j], reduced[i]-reduced[j]
+			switch {
+			case dRaw == 0 && dReduced == 0:
+			case dRaw == 0:
+				tiedRaw++
+			case dReduced == 0:
+				tiedReduced++
+			case (dRaw > 0) == (dReduced > 0):
+				concordant++
+			default:
+				discordant++
+			}
+		}
+	}
+
+	denominator := math.Sqrt((concordant + discordant + tiedReduced) * (concordant + discordant + tiedRaw))
+	if denominator == 0 {
+		return 0, errors.New("correlation is undefined for constant distances")
+	}
+	return (concordant - discordant) / denominator, nil
+}
+
+// SeparationMarginObjective is the difference between the smallest reduced
+// distance in the far set and the largest reduced distance in the close set.
+// It is positive when the reduced distances perfectly separate both sets. Failed
+// records are left out.
+type SeparationMarginObjective struct{}
+
+// Name implements the Objective interface
+func (SeparationMarginObjective) Name() string { return "separation_margin" }
+
+// Minimize implements the Objective interface
+func (SeparationMarginObjective) Minimize() bool { return false }
+
+// Evaluate implements the Objective interface
+func (SeparationMarginObjective) Evaluate(closeSet, farSet []DistanceRecord) (float64, error) {
+	if len(closeSet) == 0 || len(farSet) == 0 {
+		return 0, errors.New("the close and far sets must not be empty")
+	}
+	maxClose, minFar := math.Inf(-1), math.Inf(1)
+	var nClose, nFar int
+	for _, record := range closeSet {
+		if !isFailedRecord(record) {
+			maxClose = math.Max(maxClose, record.ReducedDistance)
+			nClose++
+		}
+	}
+	for _, record := range farSet {
+		if !isFailedRecord(record) {
+			minFar = math.Min(minFar, record.ReducedDistance)
+			nFar++
+		}
+	}
+	if nClose == 0 || nFar == 0 {
+		return 0, errors.New("the close and far sets must not only hold failed records")
+	}
+	return minFar - maxClose, nil
+}
+
+// splitDistances returns the raw and reduced distances of all the records in both
+// sets, leaving out failed records (see FilterFailedRecords) whose NaN distances
+// cannot be ranked
+func splitDistances(closeSet, farSet []DistanceRecord) ([]float64, []float64) {
+	raw := make([]float64, 0, len(closeSet)+len(farSet))
+	reduced := make([]float64, 0, len(closeSet)+len(farSet))
+	for _, set := range [][]DistanceRecord{closeSet, farSet} {
+		for _, record := range set {
+			if isFailedRecord(record) {
+				continue
+			}
+			raw = append(raw, record.RawDistance)
+			reduced = append(reduced, record.ReducedDistance)
+		}
+	}
+	return raw, reduced
+}
+
+// rank returns the ranks of the values, tied values getting the average of their ranks
+func rank(values []float64) []float64 {
+	order := make([]int, len(values))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return values[order[i]] < values[order[j]] })
+
+	ranks := make([]float64, len(values))
+	for i := 0; i < len(order); {
+		j := i
+		for j+1 < len(order) && values[order[j+1]] == values[order[i]] {
+			j++
+		}
+		average := float64(i+j)/2 + 1
+		for ; i <= j; i++ {
+			ranks[order[i]] = average
+		}
+	}
+	return ranks
+}
@@ -0,0 +1,122 @@
+package reductions
+
+import (
+	"math"
+	"testing"
+)
+
+var objectiveCloseSet = []DistanceRecord{
+	{Key1: "k1", Key2: "k2", RawDistance: 0.1, ReducedDistance: 0.1},
+	{Key1: "k3", Key2: "k4", RawDistance: 0.2, ReducedDistance: 0.3},
+}
+
+var objectiveFarSet = []DistanceRecord{
+	{Key1: "k5", Key2: "k6", RawDistance: 0.5, ReducedDistance: 0.5},
+	{Key1: "k7", Key2: "k8", RawDistance: 0.8, ReducedDistance: 0.4},
+}
+
+func TestRank(t *testing.T) {
+	ans := rank([]float64{0.5, 0.1, 0.5, 0.3})
+	wanted := []float64{3.5, 1, 3.5, 2}
+	for i := range wanted {
+		if ans[i] != wanted[i] {
+			t.Errorf("wanted %v got %v", wanted, ans)
+			break
+		}
+	}
+}
+
+func TestObjectives(t *testing.T) {
+	phi := ObjectivePhi(objectiveCloseSet, objectiveFarSet)
+	tests := []struct {
+		objective Objective
+		wanted    float64
+	}{
+		{objective: PhiObjective{}, wanted: phi.Phi},
+		{objective: WeightedPhiObjective{CloseWeight: 1, FarWeight: 1}, wanted: phi.Phi},
+		{objective: WeightedPhiObjective{CloseWeight: 2, FarWeight: 0}, wanted: 2 * phi.C},
+		// ranks raw: 1 2 3 4, reduced: 1 2 4 3
+		{objective: SpearmanObjective{}, wanted: 0.8},
+		// 5 concordant and 1 discordant pairs
+		{objective: KendallObjective{}, wanted: 4. / 6.},
+		{objective: SeparationMarginObjective{}, wanted: 0.1},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.objective.Name(), func(t *testing.T) {
+			ans, err := testCase.objective.Evaluate(objectiveCloseSet, objectiveFarSet)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if math.Abs(ans-testCase.wanted) > 1e-12 {
+				t.Errorf("wanted %v got %v", testCase.wanted, ans)
+			}
+		})
+	}
+}
+
+func TestObjectivesFailedRecords(t *testing.T) {
+	closeSet := append([]DistanceRecord{
+		{Key1: "f1", Key2: "f2", Failed: true, RawDistance: math.NaN(), ReducedDistance: math.NaN()},
+	}, objectiveCloseSet...)
+	farSet := append([]DistanceRecord{
+		{Key1: "f3", Key2: "f4", RawDistance: 0.6, ReducedDistance: math.NaN()},
+	}, objectiveFarSet...)
+	objectives := []Objective{SpearmanObjective{}, KendallObjective{}, SeparationMarginObjective{}}
+	for _, objective := range objectives {
+		t.Run(objective.Name(), func(t *testing.T) {
+			wanted, _ := objective.Evaluate(objectiveCloseSet, objectiveFarSet)
+			ans, err := objective.Evaluate(closeSet, farSet)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if ans != wanted {
+				t.Errorf("wanted %v got %v", wanted, ans)
+			}
+		})
+	}
+
+	onlyFailed := []DistanceRecord{{Key1: "f1", Key2: "f2", Failed: true}}
+	if _, err := (SeparationMarginObjective{}).Evaluate(onlyFailed, objectiveFarSet); err == nil {
+		t.Errorf("expected an error when all close records failed")
+	}
+}
+
+func TestObjectiveErrors(t *testing.T) {
+	constant := []DistanceRecord{
+		{Key1: "k1", Key2: "k2", RawDistance: 0.5, ReducedDistance: 0.1},
+		{Key1: "k3", Key2: "k4", RawDistance: 0.5, ReducedDistance: 0.3},
+	}
+	tests := []struct {
+		name             string
+		objective        Objective
+		closeSet, farSet []DistanceRecord
+	}{
+		{name: "SpearmanTooFew", objective: SpearmanObjective{}, closeSet: objectiveCloseSet[:1]},
+		{name: "SpearmanConstant", objective: SpearmanObjective{}, closeSet: constant},
+		{name: "KendallConstant", objective: KendallObjective{}, closeSet: constant},
+		{name: "MarginEmpty", objective: SeparationMarginObjective{}, closeSet: objectiveCloseSet},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			if _, err := testCase.objective.Evaluate(testCase.closeSet, testCase.farSet); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
+
+func TestEvaluateObjectives(t *testing.T) {
+	objectives := []Objective{PhiObjective{}, SpearmanObjective{}}
+	scores, err := EvaluateObjectives(objectives, objectiveCloseSet, objectiveFarSet)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(scores) != 2 || scores[0].Name != "phi" || scores[1].Name != "spearman" {
+		t.Errorf("unexpected scores %v", scores)
+	}
+
+	_, err = EvaluateObjectives([]Objective{SeparationMarginObjective{}}, objectiveCloseSet, nil)
+	if err == nil {
+		t.Errorf("expected an error")
+	}
+}
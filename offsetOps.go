@@ -0,0 +1,228 @@
+package reductions
+
+import (
+	"fmt"
+	"github.com/hillbig/rsdic"
+	"strconv"
+	"strings"
+)
+
+// Offset operations: raw positions are matched to a reduced position or deleted
+const (
+	OffsetMatch    byte = 'M'
+	OffsetDeletion byte = 'D'
+)
+
+// OffsetOp is a run of Length raw positions that are all kept (OffsetMatch) or all
+// deleted (OffsetDeletion)
+type OffsetOp struct {
+	Kind   byte
+	Length int
+}
+
+// OffsetOps is the run-length encoding of the positions kept by a reduction. With the
+// raw read as reference and the reduced read as query it is a valid SAM CIGAR.
+type OffsetOps []OffsetOp
+
+// ParseOffsets parses offsets written as operations followed by lengths (e.g.
+// "M1D4M2", as returned by MakeReductionFunctionKeepOffsets) or as a SAM CIGAR with
+// lengths followed by operations (e.g. "1M4D2M"). "*" and "" are empty offsets.
+// Runs of length 0 are skipped and successive runs of the same kind are merged.
+func ParseOffsets(offsets string) (OffsetOps, error) {
+	ops := make(OffsetOps, 0)
+	if offsets == "" || offsets == "*" {
+		return ops, nil
+	}
+	cigar := offsets[0] >= '0' && offsets[0] <= '9'
+	for i := 0; i < len(offsets); {
+		j := i
+		if !cigar {
+			j++
+		}
+		start := j
+		for j < len(offsets) && offsets[j] >= '0' && offsets[j] <= '9' {
+			j++
+		}
+		var kind byte
+		var digits string
+		if cigar {
+			if j >= len(offsets) {
+				return nil, fmt.Errorf("missing operation at the end of offsets %q", offsets)
+			}
+			kind, digits = offsets[j], offsets[start:j]
+			j++
+		} else {
+			kind, digits = offsets[i], offsets[start:j]
+		}
+		if kind != OffsetMatch && kind != OffsetDeletion {
+			return nil, fmt.Errorf("unknown operation %q in offsets %q", kind, offsets)
+		}
+		length, err := strconv.Atoi(digits)
+		if err != nil {
+			return nil, fmt.Errorf("invalid length for operation %q in offsets %q", kind, offsets)
+		}
+		ops = ops.appendInPlace(kind, length)
+		i = j
+	}
+	return ops, nil
+}
+
+// OffsetsFromBitVector encodes a bit vector of kept positions as offsets
+func OffsetsFromBitVector(kept *rsdic.RSDic) OffsetOps {
+	ops := make(OffsetOps, 0)
+	for i := uint64(0); i < kept.Num(); i++ {
+		if kept.Bit(i) {
+			ops = ops.appendInPlace(OffsetMatch, 1)
+		} else {
+			ops = ops.appendInPlace(OffsetDeletion, 1)
+		}
+	}
+	return ops
+}
+
+// Append adds a run to the offsets, merging it with the last run if they are of the
+// same kind. Runs of length 0 are skipped. The receiver is left unchanged: a merged run
+// is written to a copy, and like the append builtin the result may otherwise share the
+// receiver's backing array.
+func (ops OffsetOps) Append(kind byte, length int) OffsetOps {
+	if last := len(ops) - 1; length != 0 && last >= 0 && ops[last].Kind == kind {
+		merged := make(OffsetOps, len(ops), len(ops)+1)
+		copy(merged, ops)
+		merged[last].Length += length
+		return merged
+	}
+	return ops.appendInPlace(kind, length)
+}
+
+// appendInPlace is Append merging runs in place, for offsets built by the caller
+func (ops OffsetOps) appendInPlace(kind byte, length int) OffsetOps {
+	if length == 0 {
+		return ops
+	}
+	if last := len(ops) - 1; last >= 0 && ops[last].Kind == kind {
+		ops[last].Length += length
+		return ops
+	}
+	return append(ops, OffsetOp{Kind: kind, Length: length})
+}
+
+// Validate checks that the offsets only have positive matches and deletions, with no
+// successive runs of the same kind
+func (ops OffsetOps) Validate() error {
+	for i, op := range ops {
+		if op.Kind != OffsetMatch && op.Kind != OffsetDeletion {
+			return fmt.Errorf("unknown operation %q at index %d", op.Kind, i)
+		}
+		if op.Length <= 0 {
+			return fmt.Errorf("operation %d has length %d", i, op.Length)
+		}
+		if i > 0 && ops[i-1].Kind == op.Kind {
+			return fmt.Errorf("operations %d and %d are both %q", i-1, i, op.Kind)
+		}
+	}
+	return nil
+}
+
+// RawLength returns the number of raw positions covered by the offsets
+func (ops OffsetOps) RawLength() int {
+	length := 0
+	for _, op := range ops {
+		length += op.Length
+	}
+	return length
+}
+
+// ReducedLength returns the number of kept positions
+func (ops OffsetOps) ReducedLength() int {
+	length := 0
+	for _, op := range ops {
+		if op.Kind == OffsetMatch {
+			length += op.Length
+		}
+	}
+	return length
+}
+
+// String writes the offsets as operations followed by lengths, e.g. "M1D4M2"
+func (ops OffsetOps) String() string {
+	var builder strings.Builder
+	for _, op := range ops {
+		builder.WriteByte(op.Kind)
+		builder.WriteString(strconv.Itoa(op.Length))
+	}
+	return builder.String()
+}
+
+// CIGAR writes the offsets as a SAM CIGAR, e.g. "1M4D2M", or "*" if they are empty
+func (ops OffsetOps) CIGAR() string {
+	if len(ops) == 0 {
+		return "*"
+	}
+	var builder strings.Builder
+	for _, op := range ops {
+		builder.WriteString(strconv.Itoa(op.Length))
+		builder.WriteByte(op.Kind)
+	}
+	return builder.String()
+}
+
+// BitVector returns the bit vector of kept positions
+func (ops OffsetOps) BitVector() *rsdic.RSDic {
+	kept := rsdic.New()
+	for _, op := range ops {
+		for i := 0; i < op.Length; i++ {
+			kept.PushBack(op.Kind == OffsetMatch)
+		}
+	}
+	return kept
+}
+
+// Concat returns the offsets of the concatenation of two reads
+func (ops OffsetOps) Concat(other OffsetOps) OffsetOps {
+	result := make(OffsetOps, 0, len(ops)+len(other))
+	for _, op := range ops {
+		result = result.appendInPlace(op.Kind, op.Length)
+	}
+	for _, op := range other {
+		result = result.appendInPlace(op.Kind, op.Length)
+	}
+	return result
+}
+
+// ComposeOffsets returns the offsets of a read reduced by two successive reductions,
+// first applied to the raw read and second applied to its reduced read
+func ComposeOffsets(first, second OffsetOps) (OffsetOps, error) {
+	if err := first.Validate(); err != nil {
+		return nil, fmt.Errorf("first offsets: %w", err)
+	}
+	if err := second.Validate(); err != nil {
+		return nil, fmt.Errorf("second offsets: %w", err)
+	}
+	if first.ReducedLength() != second.RawLength() {
+		return nil, fmt.Errorf("first offsets keep %d positions but second offsets cover %d", first.ReducedLength(), second.RawLength())
+	}
+	result := make(OffsetOps, 0)
+	j, remaining := 0, 0
+	if len(second) > 0 {
+		remaining = second[0].Length
+	}
+	for _, op := range first {
+		if op.Kind == OffsetDeletion {
+			result = result.appendInPlace(OffsetDeletion, op.Length)
+			continue
+		}
+		// kept positions are kept or deleted by the second reduction
+		for length := op.Length; length > 0; {
+			if remaining == 0 {
+				j++
+				remaining = second[j].Length
+				continue
+			}
+			step := minInt(length, remaining)
+			result = result.appendInPlace(second[j].Kind, step)
+			length -= step
+			remaining -= step
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,159 @@
+package reductions
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestParseOffsets(t *testing.T) {
+	var tests = []struct {
+		name, offsets string
+		wanted        OffsetOps
+	}{
+		{name: "Empty", offsets: "", wanted: OffsetOps{}},
+		{name: "Star", offsets: "*", wanted: OffsetOps{}},
+		{name: "Legacy", offsets: "M1D4M2", wanted: OffsetOps{{'M', 1}, {'D', 4}, {'M', 2}}},
+		{name: "CIGAR", offsets: "1M4D2M", wanted: OffsetOps{{'M', 1}, {'D', 4}, {'M', 2}}},
+		{name: "LegacyZero", offsets: "M0D3M12", wanted: OffsetOps{{'D', 3}, {'M', 12}}},
+		{name: "Merged", offsets: "2M3M1D", wanted: OffsetOps{{'M', 5}, {'D', 1}}},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			ans, err := ParseOffsets(testCase.offsets)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(ans, testCase.wanted) {
+				t.Errorf("%v (got)\n%v (wanted)", ans, testCase.wanted)
+			}
+			if err := ans.Validate(); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestParseOffsetsErrors(t *testing.T) {
+	for _, offsets := range []string{"X3", "M", "MD2", "M-1", "3X", "3", "1M2"} {
+		t.Run(offsets, func(t *testing.T) {
+			if _, err := ParseOffsets(offsets); err == nil {
+				t.Errorf("Was expecting error when parsing %q", offsets)
+			}
+		})
+	}
+}
+
+func TestOffsetOpsValidate(t *testing.T) {
+	var tests = []struct {
+		name string
+		ops  OffsetOps
+	}{
+		{name: "UnknownKind", ops: OffsetOps{{'I', 2}}},
+		{name: "ZeroLength", ops: OffsetOps{{'M', 0}}},
+		{name: "NegativeLength", ops: OffsetOps{{'D', -1}}},
+		{name: "NotMerged", ops: OffsetOps{{'M', 1}, {'M', 2}}},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			if err := testCase.ops.Validate(); err == nil {
+				t.Errorf("Was expecting error when validating %v", testCase.ops)
+			}
+		})
+	}
+}
+
+func TestOffsetOpsFormats(t *testing.T) {
+	ops := OffsetOps{{'M', 1}, {'D', 4}, {'M', 2}}
+	if ops.String() != "M1D4M2" || ops.CIGAR() != "1M4D2M" {
+		t.Errorf("%s, %s (got)\nM1D4M2, 1M4D2M (wanted)", ops.String(), ops.CIGAR())
+	}
+	if (OffsetOps{}).CIGAR() != "*" || (OffsetOps{}).String() != "" {
+		t.Errorf("%q, %q (got)\n\"*\", \"\" (wanted)", OffsetOps{}.CIGAR(), OffsetOps{}.String())
+	}
+	if ops.RawLength() != 7 || ops.ReducedLength() != 3 {
+		t.Errorf("%d, %d (got)\n7, 3 (wanted)", ops.RawLength(), ops.ReducedLength())
+	}
+	if back := OffsetsFromBitVector(ops.BitVector()); !reflect.DeepEqual(back, ops) {
+		t.Errorf("%v (got)\n%v (wanted)", back, ops)
+	}
+}
+
+func TestOffsetOpsConcat(t *testing.T) {
+	a := OffsetOps{{'M', 1}, {'D', 2}}
+	b := OffsetOps{{'D', 1}, {'M', 3}}
+	wanted := OffsetOps{{'M', 1}, {'D', 3}, {'M', 3}}
+	if ans := a.Concat(b); !reflect.DeepEqual(ans, wanted) {
+		t.Errorf("%v (got)\n%v (wanted)", ans, wanted)
+	}
+	if !reflect.DeepEqual(a, OffsetOps{{'M', 1}, {'D', 2}}) {
+		t.Errorf("Concat modified its receiver: %v", a)
+	}
+}
+
+func TestOffsetOpsAppend(t *testing.T) {
+	var tests = []struct {
+		name   string
+		kind   byte
+		length int
+		wanted OffsetOps
+	}{
+		{name: "Merge", kind: 'D', length: 3, wanted: OffsetOps{{'M', 1}, {'D', 5}}},
+		{name: "New", kind: 'M', length: 3, wanted: OffsetOps{{'M', 1}, {'D', 2}, {'M', 3}}},
+		{name: "Empty", kind: 'M', length: 0, wanted: OffsetOps{{'M', 1}, {'D', 2}}},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			a := make(OffsetOps, 0, 4)
+			a = append(a, OffsetOp{'M', 1}, OffsetOp{'D', 2})
+			if b := a.Append(testCase.kind, testCase.length); !reflect.DeepEqual(b, testCase.wanted) {
+				t.Errorf("%v (got)\n%v (wanted)", b, testCase.wanted)
+			}
+			if !reflect.DeepEqual(a, OffsetOps{{'M', 1}, {'D', 2}}) {
+				t.Errorf("Append modified its receiver: %v", a)
+			}
+		})
+	}
+}
+
+func TestComposeOffsets(t *testing.T) {
+	rng := rand.New(rand.NewSource(13))
+	read := randomSequence(rng, 300)
+	first := MakeReductionFunctionKeepOffsets(hpcSurjection)
+	second := MakeReductionFunctionKeepOffsets(map[string]string{
+		"AA": "A", "AC": ".", "AG": "G", "AT": "T",
+		"CA": "A", "CC": "C", "CG": ".", "CT": "T",
+		"GA": "A", "GC": "C", "GG": "G", "GT": ".",
+		"TA": ".", "TC": "C", "TG": "G", "TT": "T",
+	})
+
+	reduced, firstOffsets := first(read)
+	twice, secondOffsets := second(reduced)
+	firstOps, err := ParseOffsets(firstOffsets)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	secondOps, err := ParseOffsets(secondOffsets)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	composed, err := ComposeOffsets(firstOps, secondOps)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if composed.RawLength() != len(read) || composed.ReducedLength() != len(twice) {
+		t.Fatalf("%d, %d (got lengths)\n%d, %d (wanted)", composed.RawLength(), composed.ReducedLength(), len(read), len(twice))
+	}
+
+	m := NewCoordinateMap(composed.BitVector())
+	for i := 0; i < len(twice); i++ {
+		raw, err := m.ReducedToRaw(i)
+		if err != nil || read[raw] != twice[i] {
+			t.Errorf("reduced %d lifted to raw %d: %c != %c (%v)", i, raw, twice[i], read[raw], err)
+		}
+	}
+
+	if _, err := ComposeOffsets(OffsetOps{{'M', 2}}, OffsetOps{{'M', 3}}); err == nil {
+		t.Error("Was expecting error when composing offsets of different lengths")
+	}
+}
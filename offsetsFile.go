@@ -0,0 +1,382 @@
+package reductions
+
+import (
+	"bufio"
+	"bytes"
+	"container/list"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/hillbig/rsdic"
+	"io"
+	"os"
+	"sort"
+	"sync"
+)
+
+// offsetsFileMagic starts and ends every offsets file
+var offsetsFileMagic = [4]byte{'S', 'R', 'O', 'F'}
+
+// offsetsFileVersion is the version of the offsets file format
+const offsetsFileVersion = 1
+
+// offsetsHeaderSize is the size of the magic and version at the start of the file,
+// offsetsFooterSize the size of the index position and magic at its end
+const (
+	offsetsHeaderSize = 5
+	offsetsFooterSize = 12
+)
+
+// offsetsEntry locates the serialized bit vector of a read in an offsets file
+type offsetsEntry struct {
+	offset, length int64
+}
+
+// OffsetsWriter writes the bit vectors of kept positions of reduced reads to an offsets
+// file. The file holds the serialized bit vectors one after the other, followed by an
+// index of their positions by read ID, so that they can be loaded one at a time.
+type OffsetsWriter struct {
+	writer *bufio.Writer
+	pos    int64
+	ids    []string
+	index  map[string]offsetsEntry
+}
+
+// NewOffsetsWriter starts an offsets file, Close must be called to write its index
+func NewOffsetsWriter(w io.Writer) (*OffsetsWriter, error) {
+	writer := &OffsetsWriter{writer: bufio.NewWriter(w), index: make(map[string]offsetsEntry)}
+	header := append(offsetsFileMagic[:], offsetsFileVersion)
+	if err := writer.write(header); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+// write writes bytes and keeps track of the position in the file
+func (w *OffsetsWriter) write(data []byte) error {
+	n, err := w.writer.Write(data)
+	w.pos += int64(n)
+	return err
+}
+
+// Write adds the bit vector of a read, returning an error if the read ID was already written
+func (w *OffsetsWriter) Write(id string, kept *rsdic.RSDic) error {
+	if _, ok := w.index[id]; ok {
+		return fmt.Errorf("duplicate read ID %s", id)
+	}
+	data, err := kept.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	w.index[id] = offsetsEntry{offset: w.pos, length: int64(len(data))}
+	w.ids = append(w.ids, id)
+	return w.write(data)
+}
+
+// Close writes the index and the footer of the offsets file. It does not close the
+// underlying writer.
+func (w *OffsetsWriter) Close() error {
+	indexPos := w.pos
+	var buffer bytes.Buffer
+	varint := make([]byte, binary.MaxVarintLen64)
+	writeUvarint := func(x uint64) {
+		buffer.Write(varint[:binary.PutUvarint(varint, x)])
+	}
+	writeUvarint(uint64(len(w.ids)))
+	for _, id := range w.ids {
+		entry := w.index[id]
+		writeUvarint(uint64(len(id)))
+		buffer.WriteString(id)
+		writeUvarint(uint64(entry.offset))
+		writeUvarint(uint64(entry.length))
+	}
+	footer := make([]byte, 8, offsetsFooterSize)
+	binary.LittleEndian.PutUint64(footer, uint64(indexPos))
+	buffer.Write(append(footer, offsetsFileMagic[:]...))
+
+	if err := w.write(buffer.Bytes()); err != nil {
+		return err
+	}
+	return w.writer.Flush()
+}
+
+// WriteOffsetsFile writes the bit vectors of reads to an offsets file, in the given
+// order of read IDs or sorted by read ID if it is empty
+func WriteOffsetsFile(path string, offsets map[string]*rsdic.RSDic, order []string) error {
+	if len(order) == 0 {
+		order = make([]string, 0, len(offsets))
+		for id := range offsets {
+			order = append(order, id)
+		}
+		sort.Strings(order)
+	} else if len(order) != len(offsets) {
+		return errors.New("Key order and offsets must have the same length")
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	writer, err := NewOffsetsWriter(file)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	for _, id := range order {
+		kept, ok := offsets[id]
+		if !ok {
+			file.Close()
+			return fmt.Errorf("no offsets for read %s", id)
+		}
+		if err := writer.Write(id, kept); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// DefaultOffsetsCacheSize is the number of decoded bit vectors an OffsetsReader keeps
+const DefaultOffsetsCacheSize = 1024
+
+// OffsetsReader loads the bit vectors of an offsets file by read ID. Only the index is
+// read when opening the file, bit vectors are read and decoded on access and the most
+// recently used ones are cached (see SetCacheSize). It is safe for concurrent use.
+type OffsetsReader struct {
+	reader io.ReaderAt
+	closer io.Closer
+	ids    []string
+	index  map[string]offsetsEntry
+
+	mu        sync.Mutex
+	cacheSize int
+	cache     map[string]*list.Element
+	recent    *list.List // cached offsetsCacheEntry, most recently used first
+}
+
+// offsetsCacheEntry is a decoded bit vector cached by an OffsetsReader
+type offsetsCacheEntry struct {
+	id   string
+	kept *rsdic.RSDic
+}
+
+// NewOffsetsReader reads the index of an offsets file of the given size
+func NewOffsetsReader(r io.ReaderAt, size int64) (*OffsetsReader, error) {
+	if size < offsetsHeaderSize+offsetsFooterSize {
+		return nil, errors.New("offsets file is too short")
+	}
+	header := make([]byte, offsetsHeaderSize)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(header[:4], offsetsFileMagic[:]) {
+		return nil, errors.New("not an offsets file")
+	}
+	if header[4] != offsetsFileVersion {
+		return nil, fmt.Errorf("unsupported offsets file version %d", header[4])
+	}
+
+	footer := make([]byte, offsetsFooterSize)
+	if _, err := r.ReadAt(footer, size-offsetsFooterSize); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(footer[8:], offsetsFileMagic[:]) {
+		return nil, errors.New("offsets file has no index, it may be truncated")
+	}
+	indexPos := int64(binary.LittleEndian.Uint64(footer))
+	if indexPos < offsetsHeaderSize || indexPos > size-offsetsFooterSize {
+		return nil, errors.New("invalid index position in offsets file")
+	}
+
+	data := make([]byte, size-offsetsFooterSize-indexPos)
+	if _, err := r.ReadAt(data, indexPos); err != nil {
+		return nil, err
+	}
+	reader := &OffsetsReader{
+		reader:    r,
+		index:     make(map[string]offsetsEntry),
+		cacheSize: DefaultOffsetsCacheSize,
+		cache:     make(map[string]*list.Element),
+		recent:    list.New(),
+	}
+	if err := reader.parseIndex(bytes.NewReader(data), indexPos); err != nil {
+		return nil, fmt.Errorf("offsets file index: %w", err)
+	}
+	return reader, nil
+}
+
+// parseIndex decodes the index, checking that entries lie before it
+func (r *OffsetsReader) parseIndex(data *bytes.Reader, indexPos int64) error {
+	count, err := binary.ReadUvarint(data)
+	if err != nil {
+		return err
+	}
+	for i := uint64(0); i < count; i++ {
+		idLength, err := binary.ReadUvarint(data)
+		if err != nil {
+			return err
+		}
+		if idLength > uint64(data.Len()) {
+			return io.ErrUnexpectedEOF
+		}
+		id := make([]byte, idLength)
+		data.Read(id)
+		offset, err := binary.ReadUvarint(data)
+		if err != nil {
+			return err
+		}
+		length, err := binary.ReadUvarint(data)
+		if err != nil {
+			return err
+		}
+		if offset < offsetsHeaderSize || offset+length > uint64(indexPos) {
+			return fmt.Errorf("entry of read %s is out of the file", id)
+		}
+		r.ids = append(r.ids, string(id))
+		r.index[string(id)] = offsetsEntry{offset: int64(offset), length: int64(length)}
+	}
+	if data.Len() != 0 {
+		return errors.New("trailing data")
+	}
+	return nil
+}
+
+// OpenOffsetsFile opens an offsets file, which must be closed with Close
+func OpenOffsetsFile(path string) (*OffsetsReader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	reader, err := NewOffsetsReader(file, info.Size())
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	reader.closer = file
+	return reader, nil
+}
+
+// Close closes the file opened by OpenOffsetsFile
+func (r *OffsetsReader) Close() error {
+	if r.closer == nil {
+		return nil
+	}
+	return r.closer.Close()
+}
+
+// IDs returns the read IDs in the order they were written
+func (r *OffsetsReader) IDs() []string {
+	return append([]string{}, r.ids...)
+}
+
+// Has returns true if the file holds the offsets of a read
+func (r *OffsetsReader) Has(id string) bool {
+	_, ok := r.index[id]
+	return ok
+}
+
+// SetCacheSize sets the number of decoded bit vectors kept in memory, 0 disabling the cache
+func (r *OffsetsReader) SetCacheSize(size int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cacheSize = size
+	r.evict()
+}
+
+// evict removes the least recently used bit vectors until the cache fits its size
+func (r *OffsetsReader) evict() {
+	for r.recent.Len() > 0 && r.recent.Len() > r.cacheSize {
+		oldest := r.recent.Back()
+		r.recent.Remove(oldest)
+		delete(r.cache, oldest.Value.(offsetsCacheEntry).id)
+	}
+}
+
+// cached returns the cached bit vector of a read, or nil if it is not cached
+func (r *OffsetsReader) cached(id string) *rsdic.RSDic {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	element, ok := r.cache[id]
+	if !ok {
+		return nil
+	}
+	r.recent.MoveToFront(element)
+	return element.Value.(offsetsCacheEntry).kept
+}
+
+// store caches the bit vector of a read, returning the cached one instead if another
+// call decoded it first
+func (r *OffsetsReader) store(id string, kept *rsdic.RSDic) *rsdic.RSDic {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if element, ok := r.cache[id]; ok {
+		r.recent.MoveToFront(element)
+		return element.Value.(offsetsCacheEntry).kept
+	}
+	if r.cacheSize <= 0 {
+		return kept
+	}
+	r.cache[id] = r.recent.PushFront(offsetsCacheEntry{id: id, kept: kept})
+	r.evict()
+	return kept
+}
+
+// Get returns the bit vector of kept positions of a read. The file is read and
+// decoded without holding the cache lock, so concurrent calls do not wait on each other.
+func (r *OffsetsReader) Get(id string) (*rsdic.RSDic, error) {
+	if kept := r.cached(id); kept != nil {
+		return kept, nil
+	}
+	entry, ok := r.index[id]
+	if !ok {
+		return nil, fmt.Errorf("no offsets for read %s", id)
+	}
+	data := make([]byte, entry.length)
+	if _, err := r.reader.ReadAt(data, entry.offset); err != nil {
+		return nil, err
+	}
+	kept := rsdic.New()
+	if err := kept.UnmarshalBinary(data); err != nil {
+		return nil, fmt.Errorf("offsets of read %s: %w", id, err)
+	}
+	return r.store(id, kept), nil
+}
+
+// CoordinateMap returns the coordinate map of a read
+func (r *OffsetsReader) CoordinateMap(id string) (*CoordinateMap, error) {
+	kept, err := r.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	return NewCoordinateMap(kept), nil
+}
+
+// WriteReducedFastaWithOffsets reduces sequences with a mapping, and writes the reduced
+// sequences to a fasta file and their bit vectors of kept positions to an offsets file.
+// It returns an error if the mapping is invalid or if a read cannot be reduced.
+func WriteReducedFastaWithOffsets(sequences map[string]string, order []string, surjection map[string]string, fastaPath, offsetsPath string) error {
+	reduce, err := MakeReductionFunctionBitVectorWithOptions(surjection, DefaultReductionOptions)
+	if err != nil {
+		return err
+	}
+	reduced := make(map[string]string, len(sequences))
+	offsets := make(map[string]*rsdic.RSDic, len(sequences))
+	for id, sequence := range sequences {
+		if reduced[id], offsets[id], err = reduce(sequence); err != nil {
+			return fmt.Errorf("read %s: %w", id, err)
+		}
+	}
+	if err := WriteFasta(reduced, fastaPath, order); err != nil {
+		return err
+	}
+	return WriteOffsetsFile(offsetsPath, offsets, order)
+}
@@ -0,0 +1,219 @@
+package reductions
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// countingReaderAt counts the calls to ReadAt
+type countingReaderAt struct {
+	reader io.ReaderAt
+	calls  int
+}
+
+func (r *countingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	r.calls++
+	return r.reader.ReadAt(p, off)
+}
+
+func TestOffsetsFileRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(17))
+	sequences := map[string]string{
+		"read1": randomSequence(rng, 300),
+		"read2": randomSequence(rng, 1000),
+		"read3": "",
+	}
+	order := []string{"read2", "read1", "read3"}
+	dir := t.TempDir()
+	fastaPath := filepath.Join(dir, "reduced.fasta")
+	offsetsPath := filepath.Join(dir, "reduced.offsets")
+	if err := WriteReducedFastaWithOffsets(sequences, order, hpcSurjection, fastaPath, offsetsPath); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	reader, err := OpenOffsetsFile(offsetsPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer reader.Close()
+	if !reflect.DeepEqual(reader.IDs(), order) {
+		t.Errorf("%v (got)\n%v (wanted)", reader.IDs(), order)
+	}
+
+	reduce := MakeReductionFunctionBitVector(hpcSurjection)
+	for id, sequence := range sequences {
+		reduced, wanted := reduce(sequence)
+		kept, err := reader.Get(id)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if equal, s1, s2 := areBitArraysEqual(wanted, kept); !equal {
+			t.Errorf("%s: [%s] (got)\n[%s] (wanted)", id, s2, s1)
+		}
+		m, err := reader.CoordinateMap(id)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if m.RawLength() != len(sequence) || m.ReducedLength() != len(reduced) {
+			t.Errorf("%s: %d, %d (got lengths)\n%d, %d (wanted)", id, m.RawLength(), m.ReducedLength(), len(sequence), len(reduced))
+		}
+	}
+	if _, err := reader.Get("read4"); err == nil || reader.Has("read4") {
+		t.Error("Was expecting error for a missing read")
+	}
+}
+
+func TestWriteReducedFastaWithOffsetsInvalidMapping(t *testing.T) {
+	dir := t.TempDir()
+	sequences := map[string]string{"read1": "ACGT"}
+	for _, surjection := range []map[string]string{{}, {"AA": ".", "ACG": "G"}} {
+		err := WriteReducedFastaWithOffsets(sequences, nil, surjection, filepath.Join(dir, "reduced.fasta"), filepath.Join(dir, "reduced.offsets"))
+		if err == nil {
+			t.Errorf("Was expecting error for mapping %v", surjection)
+		}
+	}
+}
+
+func TestOffsetsReaderIsLazy(t *testing.T) {
+	var buffer bytes.Buffer
+	writer, err := NewOffsetsWriter(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, kept := MakeReductionFunctionBitVector(hpcSurjection)("AAACGGTTTA")
+	for _, id := range []string{"a", "b", "c"} {
+		if err := writer.Write(id, kept); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Write("a", kept); err == nil {
+		t.Error("Was expecting error for a duplicate read ID")
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	counter := &countingReaderAt{reader: bytes.NewReader(buffer.Bytes())}
+	reader, err := NewOffsetsReader(counter, int64(buffer.Len()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	opened := counter.calls
+	first, err := reader.Get("b")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, _ := reader.Get("b")
+	if counter.calls != opened+1 || first != second {
+		t.Errorf("%d reads after opening (got)\n1 (wanted)", counter.calls-opened)
+	}
+}
+
+func TestOffsetsReaderCacheSize(t *testing.T) {
+	var buffer bytes.Buffer
+	writer, _ := NewOffsetsWriter(&buffer)
+	_, kept := MakeReductionFunctionBitVector(hpcSurjection)("AAACGGTTTA")
+	for _, id := range []string{"a", "b", "c"} {
+		writer.Write(id, kept)
+	}
+	writer.Close()
+
+	var tests = []struct {
+		name   string
+		size   int
+		gets   []string
+		wanted int
+	}{
+		{name: "Disabled", size: 0, gets: []string{"a", "a", "a"}, wanted: 3},
+		{name: "LeastRecentlyUsed", size: 2, gets: []string{"a", "b", "a", "c", "a", "b"}, wanted: 4},
+		{name: "Large", size: 10, gets: []string{"a", "b", "c", "a", "b", "c"}, wanted: 3},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			counter := &countingReaderAt{reader: bytes.NewReader(buffer.Bytes())}
+			reader, err := NewOffsetsReader(counter, int64(buffer.Len()))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			reader.SetCacheSize(testCase.size)
+			opened := counter.calls
+			for _, id := range testCase.gets {
+				if _, err := reader.Get(id); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+			if counter.calls-opened != testCase.wanted {
+				t.Errorf("%d reads (got)\n%d (wanted)", counter.calls-opened, testCase.wanted)
+			}
+		})
+	}
+}
+
+func TestOffsetsReaderConcurrentGet(t *testing.T) {
+	var buffer bytes.Buffer
+	writer, _ := NewOffsetsWriter(&buffer)
+	_, kept := MakeReductionFunctionBitVector(hpcSurjection)("AAACGGTTTA")
+	ids := []string{"a", "b", "c", "d"}
+	for _, id := range ids {
+		writer.Write(id, kept)
+	}
+	writer.Close()
+	reader, err := NewOffsetsReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	reader.SetCacheSize(2)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				got, err := reader.Get(ids[(i+j)%len(ids)])
+				if err != nil || got.Num() != kept.Num() {
+					t.Errorf("unexpected result: %v", err)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestOffsetsReaderErrors(t *testing.T) {
+	var buffer bytes.Buffer
+	writer, _ := NewOffsetsWriter(&buffer)
+	_, kept := MakeReductionFunctionBitVector(hpcSurjection)("AAACGGTTTA")
+	writer.Write("a", kept)
+	writer.Close()
+	data := buffer.Bytes()
+
+	corruptedMagic := append([]byte{}, data...)
+	corruptedMagic[0] = 'X'
+	corruptedVersion := append([]byte{}, data...)
+	corruptedVersion[4] = 9
+	var tests = []struct {
+		name string
+		data []byte
+	}{
+		{name: "Empty", data: []byte{}},
+		{name: "BadMagic", data: corruptedMagic},
+		{name: "BadVersion", data: corruptedVersion},
+		{name: "Truncated", data: data[:len(data)-1]},
+		{name: "NoIndex", data: data[:len(data)-offsetsFooterSize]},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := NewOffsetsReader(bytes.NewReader(testCase.data), int64(len(testCase.data)))
+			if err == nil {
+				t.Errorf("Was expecting error when reading %v", testCase.data)
+			}
+		})
+	}
+}
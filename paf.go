@@ -0,0 +1,175 @@
+package reductions
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/hillbig/rsdic"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// PAFRecord is a line of a PAF file. Coordinates are 0-based and half-open, on the
+// forward strand of both sequences. Tags holds the optional SAM-like fields as is.
+type PAFRecord struct {
+	QueryName                            string
+	QueryLength, QueryStart, QueryEnd    int
+	Strand                               byte
+	TargetName                           string
+	TargetLength, TargetStart, TargetEnd int
+	Matches, AlignmentLength             int
+	MappingQuality                       int
+	Tags                                 []string
+}
+
+// CoordinateLifter links positions of a raw sequence to positions of its reduced
+// sequence. It is implemented by CoordinateMap and HPCCoordinates.
+type CoordinateLifter interface {
+	RawLength() int
+	ReducedLength() int
+	RawToReduced(pos int) (int, bool, error)
+	ReducedToRaw(pos int) (int, error)
+	RawIntervalToReduced(start, end int) (int, int, error)
+	ReducedIntervalToRaw(start, end int) (int, int, error)
+}
+
+// ReadPAF reads PAF records
+func ReadPAF(r io.Reader) ([]PAFRecord, error) {
+	records := make([]PAFRecord, 0)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<28)
+	for line := 1; scanner.Scan(); line++ {
+		if scanner.Text() == "" {
+			continue
+		}
+		record, err := parsePAFLine(scanner.Text())
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// parsePAFLine parses the tab separated fields of a PAF line
+func parsePAFLine(line string) (PAFRecord, error) {
+	fields := strings.Split(line, "\t")
+	if len(fields) < 12 {
+		return PAFRecord{}, fmt.Errorf("PAF lines have at least 12 fields, got %d", len(fields))
+	}
+	if fields[4] != "+" && fields[4] != "-" {
+		return PAFRecord{}, fmt.Errorf("invalid strand %q", fields[4])
+	}
+
+	record := PAFRecord{QueryName: fields[0], Strand: fields[4][0], TargetName: fields[5], Tags: fields[12:]}
+	integers := []struct {
+		index int
+		value *int
+	}{
+		{1, &record.QueryLength}, {2, &record.QueryStart}, {3, &record.QueryEnd},
+		{6, &record.TargetLength}, {7, &record.TargetStart}, {8, &record.TargetEnd},
+		{9, &record.Matches}, {10, &record.AlignmentLength}, {11, &record.MappingQuality},
+	}
+	for _, integer := range integers {
+		value, err := strconv.Atoi(fields[integer.index])
+		if err != nil {
+			return PAFRecord{}, fmt.Errorf("field %d: %w", integer.index+1, err)
+		}
+		*integer.value = value
+	}
+	return record, nil
+}
+
+// WritePAF writes PAF records
+func WritePAF(w io.Writer, records []PAFRecord) error {
+	writer := bufio.NewWriter(w)
+	for _, record := range records {
+		fmt.Fprintf(writer, "%s\t%d\t%d\t%d\t%c\t%s\t%d\t%d\t%d\t%d\t%d\t%d",
+			record.QueryName, record.QueryLength, record.QueryStart, record.QueryEnd, record.Strand,
+			record.TargetName, record.TargetLength, record.TargetStart, record.TargetEnd,
+			record.Matches, record.AlignmentLength, record.MappingQuality)
+		for _, tag := range record.Tags {
+			writer.WriteString("\t" + tag)
+		}
+		writer.WriteByte('\n')
+	}
+	return writer.Flush()
+}
+
+// LiftersFromBitVectors makes coordinate lifters from the bit vectors returned by
+// MakeReductionFunctionBitVector, keyed by sequence name
+func LiftersFromBitVectors(offsets map[string]*rsdic.RSDic) map[string]CoordinateLifter {
+	lifters := make(map[string]CoordinateLifter, len(offsets))
+	for name, kept := range offsets {
+		lifters[name] = NewCoordinateMap(kept)
+	}
+	return lifters
+}
+
+// LiftersFromRuns makes coordinate lifters from the run lengths returned by
+// HomopolymerCompressionWithRuns, keyed by sequence name
+func LiftersFromRuns(runs map[string][]int) (map[string]CoordinateLifter, error) {
+	lifters := make(map[string]CoordinateLifter, len(runs))
+	for name, run := range runs {
+		coordinates, err := NewHPCCoordinates(run)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		lifters[name] = coordinates
+	}
+	return lifters, nil
+}
+
+// LiftPAF rewrites PAF records of reduced sequences in raw coordinates. Queries and
+// targets are lifted with the lifter of their name, a nil map leaving that side as is.
+// Matches and alignment lengths are left in reduced space, and the cg and cs tags,
+// which describe the reduced alignment, are removed.
+func LiftPAF(records []PAFRecord, queries, targets map[string]CoordinateLifter) ([]PAFRecord, error) {
+	lifted := make([]PAFRecord, len(records))
+	for i, record := range records {
+		var err error
+		if queries != nil {
+			record.QueryLength, record.QueryStart, record.QueryEnd, err = liftInterval(
+				queries, record.QueryName, record.QueryLength, record.QueryStart, record.QueryEnd)
+			if err != nil {
+				return nil, fmt.Errorf("record %d, query: %w", i, err)
+			}
+		}
+		if targets != nil {
+			record.TargetLength, record.TargetStart, record.TargetEnd, err = liftInterval(
+				targets, record.TargetName, record.TargetLength, record.TargetStart, record.TargetEnd)
+			if err != nil {
+				return nil, fmt.Errorf("record %d, target: %w", i, err)
+			}
+		}
+		tags := make([]string, 0, len(record.Tags))
+		for _, tag := range record.Tags {
+			if strings.HasPrefix(tag, "cg:") || strings.HasPrefix(tag, "cs:") {
+				continue
+			}
+			tags = append(tags, tag)
+		}
+		record.Tags = tags
+		lifted[i] = record
+	}
+	return lifted, nil
+}
+
+// liftInterval lifts a reduced interval of a named sequence to raw coordinates
+func liftInterval(lifters map[string]CoordinateLifter, name string, length, start, end int) (int, int, int, error) {
+	lifter, ok := lifters[name]
+	if !ok {
+		return 0, 0, 0, fmt.Errorf("no offsets for sequence %s", name)
+	}
+	if lifter.ReducedLength() != length {
+		return 0, 0, 0, fmt.Errorf("sequence %s has length %d but its offsets keep %d positions", name, length, lifter.ReducedLength())
+	}
+	rawStart, rawEnd, err := lifter.ReducedIntervalToRaw(start, end)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("sequence %s: %w", name, err)
+	}
+	return lifter.RawLength(), rawStart, rawEnd, nil
+}
@@ -0,0 +1,119 @@
+package reductions
+
+import (
+	"bytes"
+	"github.com/hillbig/rsdic"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var (
+	_ CoordinateLifter = (*CoordinateMap)(nil)
+	_ CoordinateLifter = (*HPCCoordinates)(nil)
+)
+
+const testPAF = "read1\t5\t1\t4\t+\tref1\t6\t0\t3\t3\t3\t60\ttp:A:P\tcg:Z:3M\n" +
+	"read1\t5\t0\t2\t-\tref1\t6\t3\t5\t2\t2\t0\n"
+
+func TestPAFRoundTrip(t *testing.T) {
+	records, err := ReadPAF(strings.NewReader(testPAF))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wanted := PAFRecord{
+		QueryName: "read1", QueryLength: 5, QueryStart: 1, QueryEnd: 4, Strand: '+',
+		TargetName: "ref1", TargetLength: 6, TargetStart: 0, TargetEnd: 3,
+		Matches: 3, AlignmentLength: 3, MappingQuality: 60, Tags: []string{"tp:A:P", "cg:Z:3M"},
+	}
+	if len(records) != 2 || !reflect.DeepEqual(records[0], wanted) {
+		t.Fatalf("%+v (got)\n%+v (wanted)", records, wanted)
+	}
+	var buffer bytes.Buffer
+	if err := WritePAF(&buffer, records); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if buffer.String() != testPAF {
+		t.Errorf("%q (got)\n%q (wanted)", buffer.String(), testPAF)
+	}
+}
+
+func TestReadPAFErrors(t *testing.T) {
+	var tests = []struct {
+		name, data string
+	}{
+		{name: "TooFewFields", data: "read1\t5\t1\t4\t+\tref1\n"},
+		{name: "BadStrand", data: "read1\t5\t1\t4\t*\tref1\t6\t0\t3\t3\t3\t60\n"},
+		{name: "BadInteger", data: "read1\t5\tx\t4\t+\tref1\t6\t0\t3\t3\t3\t60\n"},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			if _, err := ReadPAF(strings.NewReader(testCase.data)); err == nil {
+				t.Errorf("Was expecting error when reading %q", testCase.data)
+			}
+		})
+	}
+}
+
+func TestLiftPAF(t *testing.T) {
+	query := "AAACGGGTTA"
+	target := "TTACCCGGGTTAA"
+	reducedQuery, queryBits := MakeReductionFunctionBitVector(hpcSurjection)(query)
+	reducedTarget, targetRuns := HomopolymerCompressionWithRuns(target)
+	if reducedQuery != "ACGTA" || reducedTarget != "TACGTA" {
+		t.Fatalf("%s, %s (got)\nACGTA, TACGTA (wanted)", reducedQuery, reducedTarget)
+	}
+
+	records, err := ReadPAF(strings.NewReader(testPAF))
+	if err != nil {
+		t.Fatal(err)
+	}
+	queries := LiftersFromBitVectors(map[string]*rsdic.RSDic{"read1": queryBits})
+	targets, err := LiftersFromRuns(map[string][]int{"ref1": targetRuns})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lifted, err := LiftPAF(records, queries, targets)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// query kept positions: A(0) C(3) G(4) T(7) A(9), reduced [1, 4) is CGT
+	// target runs: TT A CCC GGG TT AA, reduced [0, 3) is TAC and [3, 5) is GT
+	var tests = []struct {
+		qStart, qEnd, tStart, tEnd int
+		tags                       []string
+	}{
+		{qStart: 3, qEnd: 8, tStart: 0, tEnd: 6, tags: []string{"tp:A:P"}},
+		{qStart: 0, qEnd: 4, tStart: 6, tEnd: 11, tags: []string{}},
+	}
+	for i, testCase := range tests {
+		record := lifted[i]
+		if record.QueryLength != len(query) || record.TargetLength != len(target) {
+			t.Errorf("%d, %d (got lengths)\n%d, %d (wanted)", record.QueryLength, record.TargetLength, len(query), len(target))
+		}
+		if record.QueryStart != testCase.qStart || record.QueryEnd != testCase.qEnd ||
+			record.TargetStart != testCase.tStart || record.TargetEnd != testCase.tEnd {
+			t.Errorf("query [%d, %d) target [%d, %d) (got)\nquery [%d, %d) target [%d, %d) (wanted)",
+				record.QueryStart, record.QueryEnd, record.TargetStart, record.TargetEnd,
+				testCase.qStart, testCase.qEnd, testCase.tStart, testCase.tEnd)
+		}
+		if !reflect.DeepEqual(record.Tags, testCase.tags) {
+			t.Errorf("%v (got tags)\n%v (wanted)", record.Tags, testCase.tags)
+		}
+	}
+	if records[0].QueryStart != 1 || len(records[0].Tags) != 2 {
+		t.Errorf("LiftPAF modified its input: %+v", records[0])
+	}
+
+	if _, err := LiftPAF(records, map[string]CoordinateLifter{}, nil); err == nil {
+		t.Error("Was expecting error for a query without offsets")
+	}
+	if _, err := LiftPAF(records, nil, queries); err == nil {
+		t.Error("Was expecting error for a target without offsets")
+	}
+	short, _ := NewHPCCoordinates([]int{1, 1})
+	if _, err := LiftPAF(records, map[string]CoordinateLifter{"read1": short}, nil); err == nil {
+		t.Error("Was expecting error for offsets of the wrong length")
+	}
+}
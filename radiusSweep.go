@@ -0,0 +1,147 @@
+package reductions
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// RadiusSweepRow holds the objective of a reduction when the close and far sets
+// are separated with a given radius. Err is set when phi is undefined for that radius.
+type RadiusSweepRow struct {
+	Reduction    string
+	Radius       float64
+	NClose, NFar int
+	Phi          PhiRecord
+	Diagnostics  PhiDiagnostics
+	Err          error
+}
+
+// RadiiRange returns the radii from start to stop (included) with the given step
+func RadiiRange(start, stop, step float64) ([]float64, error) {
+	if step <= 0 {
+		return nil, errors.New("step must be positive")
+	}
+	if stop < start {
+		return nil, errors.New("stop must be larger than start")
+	}
+	n := int(math.Floor((stop-start)/step+1e-9)) + 1
+	radii := make([]float64, n)
+	for i := range radii {
+		radii[i] = start + float64(i)*step
+	}
+	return radii, nil
+}
+
+// SweepRadii evaluates phi for every radius on a single set of distances. The
+// records are sorted by raw distance once, and the close and far accumulators of
+// every radius are obtained with one forward and one backward pass over them.
+// Rows are returned in increasing radius order.
+func SweepRadii(distances []DistanceRecord, radii []float64) []RadiusSweepRow {
+	sortedRadii := append([]float64{}, radii...)
+	sort.Float64s(sortedRadii)
+
+	failed := 0
+	sorted := make([]DistanceRecord, 0, len(distances))
+	for _, record := range distances {
+		if isFailedRecord(record) {
+			failed++
+			continue
+		}
+		sorted = append(sorted, record)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].RawDistance < sorted[j].RawDistance })
+
+	// boundaries[i] is the number of records in the close set for radius i
+	boundaries := make([]int, len(sortedRadii))
+	for i, radius := range sortedRadii {
+		boundaries[i] = sort.Search(len(sorted), func(j int) bool { return sorted[j].RawDistance > radius })
+	}
+
+	closeAccs := make([]PhiAccumulator, len(sortedRadii))
+	acc := PhiAccumulator{}
+	position := 0
+	for i, boundary := range boundaries {
+		for ; position < boundary; position++ {
+			acc.AddClose(sorted[position])
+		}
+		closeAccs[i] = acc
+	}
+
+	farAccs := make([]PhiAccumulator, len(sortedRadii))
+	acc = PhiAccumulator{}
+	position = len(sorted)
+	for i := len(boundaries) - 1; i >= 0; i-- {
+		for ; position > boundaries[i]; position-- {
+			acc.AddFar(sorted[position-1])
+		}
+		farAccs[i] = acc
+	}
+
+	rows := make([]RadiusSweepRow, len(sortedRadii))
+	for i, radius := range sortedRadii {
+		combined := closeAccs[i]
+		combined.Merge(farAccs[i])
+		phi, diagnostics, err := combined.Result()
+		diagnostics.ExcludedFailed += failed
+		rows[i] = RadiusSweepRow{
+			Radius:      radius,
+			NClose:      boundaries[i],
+			NFar:        len(sorted) - boundaries[i],
+			Phi:         phi,
+			Diagnostics: diagnostics,
+			Err:         err,
+		}
+	}
+	return rows
+}
+
+// SweepRadiiReductions runs SweepRadii on the distances of several reductions,
+// given by name. Rows are ordered by reduction name and then by radius.
+func SweepRadiiReductions(distances map[string][]DistanceRecord, radii []float64) []RadiusSweepRow {
+	names := make([]string, 0, len(distances))
+	for name := range distances {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	rows := make([]RadiusSweepRow, 0, len(names)*len(radii))
+	for _, name := range names {
+		for _, row := range SweepRadii(distances[name], radii) {
+			row.Reduction = name
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// WriteRadiusSweepTSV writes a radius sweep as a tab separated table.
+// Terms are NaN and the error column is filled for radii where phi is undefined.
+func WriteRadiusSweepTSV(w io.Writer, rows []RadiusSweepRow) error {
+	writer := bufio.NewWriter(w)
+	if _, err := fmt.Fprintln(writer, "reduction\tradius\tn_close\tn_far\tphi\tC\tF\tmu\terror"); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		phi := row.Phi
+		errMessage := ""
+		if row.Err != nil {
+			nan := math.NaN()
+			phi = PhiRecord{Phi: nan, C: nan, F: nan, Mu: nan}
+			errMessage = row.Err.Error()
+		}
+		_, err := fmt.Fprintf(
+			writer, "%s\t%s\t%d\t%d\t%s\t%s\t%s\t%s\t%s\n",
+			row.Reduction, formatFloat(row.Radius), row.NClose, row.NFar,
+			formatFloat(phi.Phi), formatFloat(phi.C), formatFloat(phi.F), formatFloat(phi.Mu),
+			errMessage,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
@@ -0,0 +1,82 @@
+package reductions
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestRadiiRange(t *testing.T) {
+	radii, err := RadiiRange(0.1, 0.5, 0.1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(radii) != 5 || math.Abs(radii[4]-0.5) > 1e-12 {
+		t.Errorf("wanted 5 radii from 0.1 to 0.5, got %v", radii)
+	}
+	if _, err := RadiiRange(0.1, 0.5, 0); err == nil {
+		t.Errorf("expected an error for a zero step")
+	}
+	if _, err := RadiiRange(0.5, 0.1, 0.1); err == nil {
+		t.Errorf("expected an error for stop < start")
+	}
+}
+
+func TestSweepRadii(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	distances := makeBootstrapDistances(rng, 12, 0.9)
+	distances = append(distances, DistanceRecord{Key1: "a", Key2: "b", RawDistance: math.NaN(), ReducedDistance: math.NaN(), Failed: true})
+	radii := []float64{0.45, 0.05, 0.25}
+
+	rows := SweepRadii(distances, radii)
+	if len(rows) != len(radii) {
+		t.Fatalf("wanted %d rows got %d", len(radii), len(rows))
+	}
+	for i, radius := range []float64{0.05, 0.25, 0.45} {
+		row := rows[i]
+		if row.Radius != radius {
+			t.Errorf("rows should be sorted by radius, got %v at %d", row.Radius, i)
+		}
+		closeSet, farSet := MakeSequenceSets(distances[:len(distances)-1], radius)
+		wanted, _, wantedErr := ObjectivePhiChecked(closeSet, farSet)
+		if row.Err != wantedErr {
+			t.Errorf("radius %v: wanted error %v got %v", radius, wantedErr, row.Err)
+			continue
+		}
+		if row.NClose != len(closeSet) || row.NFar != len(farSet) {
+			t.Errorf("radius %v: wanted %d/%d close/far, got %d/%d", radius, len(closeSet), len(farSet), row.NClose, row.NFar)
+		}
+		if math.Abs(row.Phi.Phi-wanted.Phi) > 1e-12 || math.Abs(row.Phi.F-wanted.F) > 1e-12 {
+			t.Errorf("radius %v: wanted %v got %v", radius, wanted, row.Phi)
+		}
+		if row.Diagnostics.ExcludedFailed != 1 {
+			t.Errorf("radius %v: expected the failed record to be excluded, got %+v", radius, row.Diagnostics)
+		}
+	}
+	if rows[0].Err != ErrEmptyCloseSet {
+		t.Errorf("the smallest radius should have an empty close set, got %v", rows[0].Err)
+	}
+}
+
+func TestSweepRadiiReductions(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	distances := map[string][]DistanceRecord{
+		"identity": makeBootstrapDistances(rng, 8, 1),
+		"hpc":      makeBootstrapDistances(rng, 8, 0.8),
+	}
+	rows := SweepRadiiReductions(distances, []float64{0.2, 0.4})
+	if len(rows) != 4 || rows[0].Reduction != "hpc" || rows[2].Reduction != "identity" {
+		t.Errorf("unexpected rows %v", rows)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteRadiusSweepTSV(&buf, rows); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 5 || !strings.HasPrefix(lines[1], "hpc\t0.2\t") {
+		t.Errorf("unexpected table:\n%s", buf.String())
+	}
+}
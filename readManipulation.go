@@ -8,6 +8,9 @@ import (
 
 var basePairs = map[byte]rune{'A': 'T', 'G': 'C', 'C': 'G', 'T': 'A'}
 
+// ErrReadTooShort is returned when trying to kmerize a read shorter than k
+var ErrReadTooShort = errors.New("k is larger than the length of given read")
+
 // ReverseComplement gives the reverse complement of a given sequence
 func ReverseComplement(seq string) (string, error) {
 	if len(seq) == 0 {
@@ -41,18 +44,56 @@ func Canonize(kmer string) (string, error) {
 // Kmerize returns the set of canonical k-mers in a given sequence
 func Kmerize(seq string, k int) (StringSet, error) {
 	if len(seq) < k {
-		return nil, errors.New("k is larger than the length of given read")
+		return nil, ErrReadTooShort
 	}
 	if k <= 1 {
 		return nil, errors.New("k must be an integer > 1")
 	}
-	kmers := make([]string, 0, len(seq)-k+1)
+	kmers := make(StringSet, len(seq)-k+1)
 	for i := 0; i < len(seq)-k+1; i++ {
 		canonical, err := Canonize(seq[i : i+k])
 		if err != nil {
 			return nil, err
 		}
-		kmers = append(kmers, canonical)
+		kmers.Add(canonical)
+	}
+	return kmers, nil
+}
+
+var baseCodes = map[byte]uint64{'A': 0, 'C': 1, 'G': 2, 'T': 3}
+
+// KmerizeEncoded returns the set of canonical k-mers in a given sequence,
+// each k-mer being 2-bit encoded in an integer (so k must be <= 32)
+func KmerizeEncoded(seq string, k int) (Uint64Set, error) {
+	if len(seq) < k {
+		return nil, ErrReadTooShort
+	}
+	if k <= 1 || k > 32 {
+		return nil, errors.New("k must be an integer > 1 and <= 32")
+	}
+	mask := uint64(1)<<(2*uint(k)) - 1
+	if k == 32 {
+		mask = ^uint64(0)
+	}
+	shift := 2 * uint(k-1)
+
+	kmers := make(Uint64Set, len(seq)-k+1)
+	var forward, reverse uint64
+	for i := 0; i < len(seq); i++ {
+		code, ok := baseCodes[seq[i]]
+		if !ok {
+			return nil, fmt.Errorf("unkown nucleotide: %q", seq[i])
+		}
+		forward = (forward<<2 | code) & mask
+		reverse = reverse>>2 | (3-code)<<shift
+		if i < k-1 {
+			continue
+		}
+		if reverse < forward {
+			kmers.Add(reverse)
+		} else {
+			kmers.Add(forward)
+		}
 	}
-	return MakeSet(kmers), nil
+	return kmers, nil
 }
@@ -1,6 +1,7 @@
 package reductions
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -114,3 +115,59 @@ func TestKmerizeErrors(t *testing.T) {
 		})
 	}
 }
+
+func encodeKmer(kmer string) uint64 {
+	var code uint64
+	for i := 0; i < len(kmer); i++ {
+		code = code<<2 | baseCodes[kmer[i]]
+	}
+	return code
+}
+
+func TestKmerizeEncoded(t *testing.T) {
+	var tests = []struct {
+		name, seq string
+		k         int
+	}{
+		{name: "K3", seq: "ATCGATCAC", k: 3},
+		{name: "K3OneKmer", seq: "AAAAAAAAA", k: 3},
+		{name: "K5", seq: "GATTACAGGCATTTAGCCAGTACGATCAGG", k: 5},
+		{name: "K32", seq: "GATTACAGGCATTTAGCCAGTACGATCAGGTTAGACCA", k: 32},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			kmers, _ := Kmerize(testCase.seq, testCase.k)
+			wanted := Uint64Set{}
+			for kmer := range kmers {
+				wanted.Add(encodeKmer(kmer))
+			}
+			ans, err := KmerizeEncoded(testCase.seq, testCase.k)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !ans.IsEqual(wanted) {
+				t.Errorf("%s (got)\n%s (wanted)", ans.String(), wanted.String())
+			}
+		})
+	}
+}
+
+func TestKmerizeEncodedErrors(t *testing.T) {
+	var tests = []struct {
+		name, seq string
+		k         int
+	}{
+		{name: "KTooSmall", seq: "ATGCTGAC", k: 1},
+		{name: "KTooBig", seq: "ATGCTGAC", k: 10},
+		{name: "KOverflow", seq: strings.Repeat("ATGC", 10), k: 33},
+		{name: "UnknownBase", seq: "ATGNTGAC", k: 3},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := KmerizeEncoded(testCase.seq, testCase.k)
+			if err == nil {
+				t.Errorf("Was expecting error when kmerizing %s with k=%d", testCase.seq, testCase.k)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package reductions
 
 import (
+	"errors"
 	"fmt"
 	"math"
 	"strings"
@@ -68,16 +69,18 @@ func GetReducedDistance(closeSet []DistanceRecord) []float64 {
 	return terms
 }
 
-// SumArray returns the sum of a slice of float64
+// SumArray returns the compensated sum of a slice of float64
 func SumArray(slice []float64) float64 {
-	sum := 0.
+	sum := KahanSum{}
 	for i := range slice {
-		sum += slice[i]
+		sum.Add(slice[i])
 	}
-	return sum
+	return sum.Sum()
 }
 
-// ObjectivePhi is the objective function to evaluate the reduction function
+// ObjectivePhi is the objective function to evaluate the reduction function.
+// It returns NaN or Inf terms for empty sets or zero distances, see
+// ObjectivePhiChecked for a validated version.
 func ObjectivePhi(closeSet, farSet []DistanceRecord) PhiRecord {
 
 	C := SumArray(GetReducedDistance(closeSet)) / float64(len(closeSet))
@@ -92,3 +95,43 @@ func ObjectivePhi(closeSet, farSet []DistanceRecord) PhiRecord {
 		Mu:  mu,
 	}
 }
+
+var (
+	// ErrEmptyCloseSet is returned when phi is computed without close pairs
+	ErrEmptyCloseSet = errors.New("the close set has no valid distance record")
+	// ErrEmptyFarSet is returned when phi is computed without far pairs
+	// that have a positive raw distance
+	ErrEmptyFarSet = errors.New("the far set has no valid distance record with a positive raw distance")
+	// ErrZeroMu is returned when all the far pairs have a reduced distance of 0,
+	// which makes the F/mu term undefined
+	ErrZeroMu = errors.New("the mean distance ratio of the far set is 0")
+)
+
+// PhiDiagnostics counts the distance records left out when computing phi
+type PhiDiagnostics struct {
+	// ExcludedZeroRaw is the number of far pairs with a raw distance of 0,
+	// for which the distance ratio is undefined
+	ExcludedZeroRaw int
+	// ExcludedFailed is the number of pairs flagged as failed or with NaN distances
+	ExcludedFailed int
+}
+
+// isFailedRecord returns true if a record's distances could not be computed
+func isFailedRecord(record DistanceRecord) bool {
+	return record.Failed || math.IsNaN(record.RawDistance) || math.IsNaN(record.ReducedDistance)
+}
+
+// ObjectivePhiChecked computes the same objective as ObjectivePhi but leaves out
+// failed records and far pairs with a zero raw distance (reported in the returned
+// diagnostics), and returns ErrEmptyCloseSet, ErrEmptyFarSet or ErrZeroMu
+// instead of NaN or Inf terms.
+func ObjectivePhiChecked(closeSet, farSet []DistanceRecord) (PhiRecord, PhiDiagnostics, error) {
+	acc := PhiAccumulator{}
+	for _, record := range closeSet {
+		acc.AddClose(record)
+	}
+	for _, record := range farSet {
+		acc.AddFar(record)
+	}
+	return acc.Result()
+}
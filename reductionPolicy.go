@@ -0,0 +1,269 @@
+package reductions
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hillbig/rsdic"
+	"strings"
+)
+
+// ReductionPolicy says what a reduction function does with a read or a window it cannot map
+type ReductionPolicy int
+
+const (
+	// PassThrough keeps the unmapped read or window as is
+	PassThrough ReductionPolicy = iota
+	// Drop removes the unmapped read or window from the output
+	Drop
+	// Fail makes the reduction function return an error
+	Fail
+)
+
+// ErrUnknownWindow is returned when a window of a read is not in the mapping
+var ErrUnknownWindow = errors.New("window is not in the mapping")
+
+// ReductionOptions sets the policies of a reduction function:
+//   - ShortReads applies to reads shorter than the order of the mapping, which
+//     have no window to map. Passing them through returns them unchanged.
+//   - UnknownWindows applies to windows missing from the mapping (or mapped to ""),
+//     e.g. windows containing N or lowercase bases. Passing them through keeps the
+//     last base of the window, dropping them deletes it like ".".
+type ReductionOptions struct {
+	ShortReads, UnknownWindows ReductionPolicy
+}
+
+// DefaultReductionOptions are the options of the reduction functions made without options
+var DefaultReductionOptions = ReductionOptions{ShortReads: PassThrough, UnknownWindows: Drop}
+
+// reducer applies a mapping to reads, with a compiled table when possible
+type reducer struct {
+	order   int
+	table   *SurjectionTable
+	mapping map[string]string
+	options ReductionOptions
+}
+
+// newReducer compiles a mapping into a reducer, falling back to map lookups if the
+// mapping is not over ACGT. It returns an error if the mapping is empty or if its
+// keys have different lengths.
+func newReducer(surjection map[string]string, options ReductionOptions) (*reducer, error) {
+	order, err := SurjectionOrder(surjection)
+	if err != nil {
+		return nil, err
+	}
+	if table, err := CompileSurjection(surjection); err == nil {
+		return table.reducer(options), nil
+	}
+	return &reducer{order: order, mapping: surjection, options: options}, nil
+}
+
+// shortRead handles reads without any window, returning false if the read is long enough
+func (r *reducer) shortRead(read string) (bool, error) {
+	if len(read) >= r.order {
+		return false, nil
+	}
+	if r.options.ShortReads == Fail {
+		return true, fmt.Errorf("read of length %d is shorter than the order %d: %w", len(read), r.order, ErrReadTooShort)
+	}
+	return true, nil
+}
+
+// windowCounts holds the number of windows of a read, of windows mapped to "." and of
+// windows missing from the mapping
+type windowCounts struct {
+	windows, deleted, unknown int
+}
+
+// scan calls visit with the output of each window of a read long enough to be mapped,
+// kept being false for deleted windows. Windows are also counted into counts if it is
+// not nil.
+func (r *reducer) scan(read string, counts *windowCounts, visit func(output string, kept bool)) error {
+	if counts != nil {
+		counts.windows += len(read) - r.order + 1
+	}
+	if r.table != nil {
+		return r.scanTable(read, counts, visit)
+	}
+	for i := 0; i <= len(read)-r.order; i++ {
+		window := read[i : i+r.order]
+		s := r.mapping[window]
+		if s == "" {
+			if err := r.unknownWindow(window, i, counts, visit); err != nil {
+				return err
+			}
+			continue
+		}
+		r.visitMapped(s, counts, visit)
+	}
+	return nil
+}
+
+// scanTable is scan with a rolling index into the compiled table
+func (r *reducer) scanTable(read string, counts *windowCounts, visit func(output string, kept bool)) error {
+	table := r.table
+	mask := table.Len() - 1
+	index, valid := 0, 0
+	for i := 0; i < len(read); i++ {
+		code := tableCodes[read[i]]
+		if code < 0 {
+			valid = 0
+		} else {
+			index = (index<<2 | int(code)) & mask
+			valid++
+		}
+		if i < r.order-1 {
+			continue
+		}
+		if valid < r.order || table.lengths[index] == 0 {
+			start := i - r.order + 1
+			if err := r.unknownWindow(read[start:i+1], start, counts, visit); err != nil {
+				return err
+			}
+			continue
+		}
+		r.visitMapped(table.entry(index), counts, visit)
+	}
+	return nil
+}
+
+// visitMapped visits the output of a window found in the mapping
+func (r *reducer) visitMapped(output string, counts *windowCounts, visit func(output string, kept bool)) {
+	kept := output != "."
+	if !kept && counts != nil {
+		counts.deleted++
+	}
+	visit(output, kept)
+}
+
+// unknownWindow applies the unknown window policy to the window starting at position i
+func (r *reducer) unknownWindow(window string, i int, counts *windowCounts, visit func(output string, kept bool)) error {
+	if counts != nil {
+		counts.unknown++
+	}
+	switch r.options.UnknownWindows {
+	case PassThrough:
+		visit(window[len(window)-1:], true)
+	case Drop:
+		visit("", false)
+	default:
+		return fmt.Errorf("window %q at position %d: %w", window, i, ErrUnknownWindow)
+	}
+	return nil
+}
+
+// reduce returns the reduced read
+func (r *reducer) reduce(read string) (string, error) {
+	return r.reduceCounting(read, nil)
+}
+
+// reduceCounting is reduce, also counting the windows of the read into counts if it
+// is not nil
+func (r *reducer) reduceCounting(read string, counts *windowCounts) (string, error) {
+	if short, err := r.shortRead(read); short {
+		if err != nil || r.options.ShortReads == Drop {
+			return "", err
+		}
+		return read, nil
+	}
+
+	var builder strings.Builder
+	builder.Grow(len(read))
+	builder.WriteString(read[0 : r.order-1])
+	err := r.scan(read, counts, func(output string, kept bool) {
+		if kept {
+			builder.WriteString(output)
+		}
+	})
+	if err != nil {
+		return "", err
+	}
+	return builder.String(), nil
+}
+
+// MakeReductionFunctionWithOptions create a reduction function from a mapping, with
+// policies for short reads and unknown windows. It returns an error if the mapping is
+// empty or if its keys have different lengths.
+func MakeReductionFunctionWithOptions(surjection map[string]string, options ReductionOptions) (func(string) (string, error), error) {
+	r, err := newReducer(surjection, options)
+	if err != nil {
+		return nil, err
+	}
+	return r.reduce, nil
+}
+
+// MakeReductionFunctionKeepOffsetsWithOptions create a reduction function from a mapping
+// that also returns the offsets as a string of matches and deletions (e.g. "M1D4M2",
+// see ParseOffsets), with policies for short reads and unknown windows. It returns an
+// error if the mapping is empty or if its keys have different lengths.
+func MakeReductionFunctionKeepOffsetsWithOptions(surjection map[string]string, options ReductionOptions) (func(string) (string, string, error), error) {
+	r, err := newReducer(surjection, options)
+	if err != nil {
+		return nil, err
+	}
+	return func(read string) (string, string, error) {
+		if short, err := r.shortRead(read); short {
+			if err != nil || options.ShortReads == Drop {
+				return "", "", err
+			}
+			return read, make(OffsetOps, 0).Append(OffsetMatch, len(read)).String(), nil
+		}
+
+		var builder strings.Builder
+		ops := make(OffsetOps, 0).appendInPlace(OffsetMatch, r.order-1)
+		builder.WriteString(read[0 : r.order-1])
+		err := r.scan(read, nil, func(output string, kept bool) {
+			if kept {
+				ops = ops.appendInPlace(OffsetMatch, 1)
+				builder.WriteString(output)
+			} else {
+				ops = ops.appendInPlace(OffsetDeletion, 1)
+			}
+		})
+		if err != nil {
+			return "", "", err
+		}
+		return builder.String(), ops.String(), nil
+	}, nil
+}
+
+// MakeReductionFunctionBitVectorWithOptions create a reduction function from a mapping
+// that also returns a bit vector of the kept positions of the read, with policies for
+// short reads and unknown windows. It returns an error if the mapping is empty or if
+// its keys have different lengths.
+func MakeReductionFunctionBitVectorWithOptions(surjection map[string]string, options ReductionOptions) (func(string) (string, *rsdic.RSDic, error), error) {
+	r, err := newReducer(surjection, options)
+	if err != nil {
+		return nil, err
+	}
+	return func(read string) (string, *rsdic.RSDic, error) {
+		offsets := rsdic.New()
+		if short, err := r.shortRead(read); short {
+			if err != nil {
+				return "", nil, err
+			}
+			if options.ShortReads == Drop {
+				return "", offsets, nil
+			}
+			for i := 0; i < len(read); i++ {
+				offsets.PushBack(true)
+			}
+			return read, offsets, nil
+		}
+
+		for i := 0; i < r.order-1; i++ {
+			offsets.PushBack(true)
+		}
+		var builder strings.Builder
+		builder.WriteString(read[0 : r.order-1])
+		err := r.scan(read, nil, func(output string, kept bool) {
+			offsets.PushBack(kept)
+			if kept {
+				builder.WriteString(output)
+			}
+		})
+		if err != nil {
+			return "", nil, err
+		}
+		return builder.String(), offsets, nil
+	}, nil
+}
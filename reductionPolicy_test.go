@@ -0,0 +1,212 @@
+package reductions
+
+import (
+	"errors"
+	"github.com/hillbig/rsdic"
+	"testing"
+)
+
+// withAmbiguousKey returns a copy of a mapping with an extra key outside of ACGT, so
+// that it cannot be compiled to a table
+func withAmbiguousKey(surjection map[string]string) map[string]string {
+	mapping := make(map[string]string, len(surjection)+1)
+	for k, v := range surjection {
+		mapping[k] = v
+	}
+	mapping["XX"] = "."
+	return mapping
+}
+
+// makeReducers makes the reduction functions with options of a mapping
+func makeReducers(t *testing.T, surjection map[string]string, options ReductionOptions) (
+	func(string) (string, error), func(string) (string, string, error), func(string) (string, *rsdic.RSDic, error)) {
+	reduce, err := MakeReductionFunctionWithOptions(surjection, options)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	reduceOffsets, err := MakeReductionFunctionKeepOffsetsWithOptions(surjection, options)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	reduceBitVector, err := MakeReductionFunctionBitVectorWithOptions(surjection, options)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return reduce, reduceOffsets, reduceBitVector
+}
+
+func TestReductionShortReads(t *testing.T) {
+	var tests = []struct {
+		name, read     string
+		policy         ReductionPolicy
+		wanted, offset string
+		bits           int
+		err            bool
+	}{
+		{name: "EmptyPassThrough", read: "", policy: PassThrough, wanted: "", offset: "", bits: 0},
+		{name: "EmptyDrop", read: "", policy: Drop, wanted: "", offset: "", bits: 0},
+		{name: "EmptyFail", read: "", policy: Fail, err: true},
+		{name: "ShortPassThrough", read: "A", policy: PassThrough, wanted: "A", offset: "M1", bits: 1},
+		{name: "ShortDrop", read: "A", policy: Drop, wanted: "", offset: "", bits: 0},
+		{name: "ShortFail", read: "A", policy: Fail, err: true},
+		{name: "LongEnoughFail", read: "AC", policy: Fail, wanted: "AC", offset: "M2", bits: 2},
+	}
+	for _, surjection := range []map[string]string{hpcSurjection, withAmbiguousKey(hpcSurjection)} {
+		for _, testCase := range tests {
+			t.Run(testCase.name, func(t *testing.T) {
+				reduce, reduceOffsets, reduceBitVector := makeReducers(t, surjection, ReductionOptions{ShortReads: testCase.policy, UnknownWindows: Drop})
+
+				reduced, err := reduce(testCase.read)
+				if testCase.err {
+					if !errors.Is(err, ErrReadTooShort) {
+						t.Errorf("Was expecting ErrReadTooShort, got %v", err)
+					}
+				} else if err != nil || reduced != testCase.wanted {
+					t.Errorf("%q, %v (got)\n%q (wanted)", reduced, err, testCase.wanted)
+				}
+
+				reduced, offset, err := reduceOffsets(testCase.read)
+				if testCase.err != (err != nil) || reduced != testCase.wanted || offset != testCase.offset {
+					t.Errorf("%q, %q, %v (got)\n%q, %q (wanted)", reduced, offset, err, testCase.wanted, testCase.offset)
+				}
+
+				reduced, bits, err := reduceBitVector(testCase.read)
+				if testCase.err {
+					if err == nil {
+						t.Errorf("Was expecting error when reducing %q", testCase.read)
+					}
+					return
+				}
+				if err != nil || reduced != testCase.wanted || int(bits.Num()) != testCase.bits || int(bits.OneNum()) != testCase.bits {
+					t.Errorf("%q, %d bits, %v (got)\n%q, %d bits (wanted)", reduced, bits.Num(), err, testCase.wanted, testCase.bits)
+				}
+			})
+		}
+	}
+}
+
+func TestReductionDefaultsDoNotPanic(t *testing.T) {
+	order3 := map[string]string{"AAA": ".", "AAC": "C", "ACG": "G", "CGT": "T"}
+	for _, read := range []string{"", "A", "AC"} {
+		if reduced := MakeReductionFunction(order3)(read); reduced != read {
+			t.Errorf("%q (got)\n%q (wanted)", reduced, read)
+		}
+		if reduced := MakeReductionFunctionDeleteAmbs(order3)(read); reduced != read {
+			t.Errorf("%q (got)\n%q (wanted)", reduced, read)
+		}
+		if reduced, _ := MakeReductionFunctionKeepOffsets(order3)(read); reduced != read {
+			t.Errorf("%q (got)\n%q (wanted)", reduced, read)
+		}
+		if reduced, _ := MakeReductionFunctionBitVector(order3)(read); reduced != read {
+			t.Errorf("%q (got)\n%q (wanted)", reduced, read)
+		}
+		if reduced, _ := MakeReductionFunctionBitVectorDeleteAmbs(order3)(read); reduced != read {
+			t.Errorf("%q (got)\n%q (wanted)", reduced, read)
+		}
+	}
+}
+
+func TestReductionUnknownWindows(t *testing.T) {
+	var tests = []struct {
+		name, read     string
+		policy         ReductionPolicy
+		wanted, offset string
+		bits           []bool
+		counts         windowCounts
+		err            bool
+	}{
+		{
+			name: "PassThrough", read: "AANCCA", policy: PassThrough,
+			wanted: "ANCA", offset: "M1D1M2D1M1",
+			bits:   []bool{true, false, true, true, false, true},
+			counts: windowCounts{windows: 5, deleted: 2, unknown: 2},
+		},
+		{
+			name: "Drop", read: "AANCCA", policy: Drop,
+			wanted: "AA", offset: "M1D4M1",
+			bits:   []bool{true, false, false, false, false, true},
+			counts: windowCounts{windows: 5, deleted: 2, unknown: 2},
+		},
+		{name: "Fail", read: "AANCCA", policy: Fail, err: true},
+		{name: "Lowercase", read: "AAcCA", policy: Fail, err: true},
+		{
+			name: "NoUnknownFail", read: "AACCA", policy: Fail,
+			wanted: "ACA", offset: "M1D1M1D1M1",
+			bits:   []bool{true, false, true, false, true},
+			counts: windowCounts{windows: 4, deleted: 2},
+		},
+	}
+	for _, surjection := range []map[string]string{hpcSurjection, withAmbiguousKey(hpcSurjection)} {
+		for _, testCase := range tests {
+			t.Run(testCase.name, func(t *testing.T) {
+				options := ReductionOptions{ShortReads: PassThrough, UnknownWindows: testCase.policy}
+				reduce, reduceOffsets, reduceBitVector := makeReducers(t, surjection, options)
+
+				reduced, err := reduce(testCase.read)
+				if testCase.err {
+					if !errors.Is(err, ErrUnknownWindow) {
+						t.Errorf("Was expecting ErrUnknownWindow, got %v", err)
+					}
+				} else if err != nil || reduced != testCase.wanted {
+					t.Errorf("%q, %v (got)\n%q (wanted)", reduced, err, testCase.wanted)
+				}
+
+				reduced, offset, err := reduceOffsets(testCase.read)
+				if testCase.err != (err != nil) || reduced != testCase.wanted || offset != testCase.offset {
+					t.Errorf("%q, %q, %v (got)\n%q, %q (wanted)", reduced, offset, err, testCase.wanted, testCase.offset)
+				}
+
+				reduced, bits, err := reduceBitVector(testCase.read)
+				if testCase.err {
+					if err == nil {
+						t.Errorf("Was expecting error when reducing %q", testCase.read)
+					}
+					return
+				}
+				equal, s1, s2 := areBitArraysEqual(makeBitArray(testCase.bits), bits)
+				if err != nil || reduced != testCase.wanted || !equal {
+					t.Errorf("%q, [%s], %v (got)\n%q, [%s] (wanted)", reduced, s2, err, testCase.wanted, s1)
+				}
+
+				r, _ := newReducer(surjection, options)
+				var counts windowCounts
+				if reduced, err := r.reduceCounting(testCase.read, &counts); err != nil || reduced != testCase.wanted || counts != testCase.counts {
+					t.Errorf("%q, %+v, %v (got)\n%q, %+v (wanted)", reduced, counts, err, testCase.wanted, testCase.counts)
+				}
+			})
+		}
+	}
+}
+
+func TestReductionInvalidMappings(t *testing.T) {
+	var tests = []struct {
+		name       string
+		surjection map[string]string
+	}{
+		{name: "Empty", surjection: map[string]string{}},
+		{name: "MixedLengths", surjection: map[string]string{"AA": ".", "ACG": "G", "CC": "."}},
+		{name: "MixedLengthsNotACGT", surjection: map[string]string{"AN": ".", "ACN": "N"}},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			if _, err := MakeReductionFunctionWithOptions(testCase.surjection, DefaultReductionOptions); err == nil {
+				t.Error("Was expecting error from MakeReductionFunctionWithOptions")
+			}
+			if _, err := MakeReductionFunctionKeepOffsetsWithOptions(testCase.surjection, DefaultReductionOptions); err == nil {
+				t.Error("Was expecting error from MakeReductionFunctionKeepOffsetsWithOptions")
+			}
+			if _, err := MakeReductionFunctionBitVectorWithOptions(testCase.surjection, DefaultReductionOptions); err == nil {
+				t.Error("Was expecting error from MakeReductionFunctionBitVectorWithOptions")
+			}
+			if _, err := MakeReductionFunctionWithSidecar(testCase.surjection, DefaultReductionOptions); err == nil {
+				t.Error("Was expecting error from MakeReductionFunctionWithSidecar")
+			}
+			defer func() {
+				if recover() == nil {
+					t.Error("Was expecting MakeReductionFunction to panic")
+				}
+			}()
+			MakeReductionFunction(testCase.surjection)
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package reductions
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// ReadStats holds statistics on the reduction of a single read.
+// Windows, DeletedWindows (windows mapped to ".") and UnknownWindows (windows
+// missing from the mapping, e.g. containing N) are only filled for surjection
+// reductions, and LengthRatio is 0 for empty reads.
+type ReadStats struct {
+	ID             string         `json:"id"`
+	RawLength      int            `json:"raw_length"`
+	ReducedLength  int            `json:"reduced_length"`
+	LengthRatio    float64        `json:"length_ratio"`
+	Windows        int            `json:"windows,omitempty"`
+	DeletedWindows int            `json:"deleted_windows,omitempty"`
+	UnknownWindows int            `json:"unknown_windows,omitempty"`
+	SymbolCounts   map[string]int `json:"symbol_counts"`
+	Entropy        float64        `json:"entropy"`
+	RawKmers       int            `json:"raw_kmers"`
+	ReducedKmers   int            `json:"reduced_kmers"`
+}
+
+// ReductionStats holds per read and aggregate statistics on the reduction of a
+// dataset. RawKmers and ReducedKmers are the numbers of distinct k-mers over the
+// whole dataset, k-mers containing bases other than A, C, G and T being skipped, and Entropy is the Shannon entropy (in bits) of the symbols of
+// all the reduced reads.
+type ReductionStats struct {
+	K                     int            `json:"k"`
+	Reads                 []ReadStats    `json:"reads"`
+	RawLength             int            `json:"raw_length"`
+	ReducedLength         int            `json:"reduced_length"`
+	LengthRatio           float64        `json:"length_ratio"`
+	Windows               int            `json:"windows,omitempty"`
+	DeletedWindows        int            `json:"deleted_windows,omitempty"`
+	DeletedWindowFraction float64        `json:"deleted_window_fraction,omitempty"`
+	UnknownWindows        int            `json:"unknown_windows,omitempty"`
+	UnknownWindowFraction float64        `json:"unknown_window_fraction,omitempty"`
+	SymbolCounts          map[string]int `json:"symbol_counts"`
+	Entropy               float64        `json:"entropy"`
+	RawKmers              int            `json:"raw_kmers"`
+	ReducedKmers          int            `json:"reduced_kmers"`
+}
+
+// windowedReduction reduces a read and returns its window counts
+type windowedReduction func(string) (string, windowCounts, error)
+
+// ComputeReductionStats computes the statistics of a reduction over a dataset as
+// returned by ParseFasta. Reads are reported in the given order, or in
+// lexicographical order of their IDs if it is empty.
+func ComputeReductionStats(sequences map[string]string, order []string, reduction func(string) string, k int) (ReductionStats, error) {
+	return computeStats(sequences, order, func(read string) (string, windowCounts, error) {
+		return reduction(read), windowCounts{}, nil
+	}, k)
+}
+
+// ComputeSurjectionStats computes the statistics of a surjection reduction over a
+// dataset with the default reduction options, including the fraction of windows that
+// the surjection maps to "." and the fraction of windows missing from the mapping
+func ComputeSurjectionStats(sequences map[string]string, order []string, surjection map[string]string, k int) (ReductionStats, error) {
+	r, err := newReducer(surjection, DefaultReductionOptions)
+	if err != nil {
+		return ReductionStats{}, err
+	}
+	return computeStats(sequences, order, func(read string) (string, windowCounts, error) {
+		var counts windowCounts
+		reduced, err := r.reduceCounting(read, &counts)
+		return reduced, counts, err
+	}, k)
+}
+
+func computeStats(sequences map[string]string, order []string, reduction windowedReduction, k int) (ReductionStats, error) {
+	if len(order) == 0 {
+		order = make([]string, 0, len(sequences))
+		for key := range sequences {
+			order = append(order, key)
+		}
+		sort.Strings(order)
+	}
+
+	stats := ReductionStats{K: k, Reads: make([]ReadStats, 0, len(order)), SymbolCounts: map[string]int{}}
+	rawKmers, reducedKmers := StringSet{}, StringSet{}
+	for _, id := range order {
+		raw, ok := sequences[id]
+		if !ok {
+			return ReductionStats{}, fmt.Errorf("read %s is not in the sequences", id)
+		}
+		reduced, counts, err := reduction(raw)
+		if err != nil {
+			return ReductionStats{}, fmt.Errorf("read %s: %w", id, err)
+		}
+
+		read := ReadStats{
+			ID:             id,
+			RawLength:      len(raw),
+			ReducedLength:  len(reduced),
+			LengthRatio:    safeRatio(len(reduced), len(raw)),
+			Windows:        counts.windows,
+			DeletedWindows: counts.deleted,
+			UnknownWindows: counts.unknown,
+			SymbolCounts:   countSymbols(reduced),
+		}
+		read.Entropy = shannonEntropy(read.SymbolCounts)
+
+		kmers, err := kmerizeACGT(raw, k)
+		if err != nil {
+			return ReductionStats{}, fmt.Errorf("read %s: %w", id, err)
+		}
+		read.RawKmers = len(kmers)
+		rawKmers.UnionInPlace(kmers)
+
+		kmers, err = kmerizeACGT(reduced, k)
+		if err != nil {
+			return ReductionStats{}, fmt.Errorf("reduced read %s: %w", id, err)
+		}
+		read.ReducedKmers = len(kmers)
+		reducedKmers.UnionInPlace(kmers)
+
+		stats.Reads = append(stats.Reads, read)
+		stats.RawLength += read.RawLength
+		stats.ReducedLength += read.ReducedLength
+		stats.Windows += counts.windows
+		stats.DeletedWindows += counts.deleted
+		stats.UnknownWindows += counts.unknown
+		for symbol, count := range read.SymbolCounts {
+			stats.SymbolCounts[symbol] += count
+		}
+	}
+
+	stats.LengthRatio = safeRatio(stats.ReducedLength, stats.RawLength)
+	stats.DeletedWindowFraction = safeRatio(stats.DeletedWindows, stats.Windows)
+	stats.UnknownWindowFraction = safeRatio(stats.UnknownWindows, stats.Windows)
+	stats.Entropy = shannonEntropy(stats.SymbolCounts)
+	stats.RawKmers = len(rawKmers)
+	stats.ReducedKmers = len(reducedKmers)
+	return stats, nil
+}
+
+// WriteReductionStatsJSON writes reduction statistics as indented JSON
+func WriteReductionStatsJSON(w io.Writer, stats ReductionStats) error {
+	encoded, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(encoded, '\n'))
+	return err
+}
+
+// kmerizeACGT returns the canonical k-mers of a sequence, skipping the k-mers that
+// contain a base other than A, C, G or T (e.g. N)
+func kmerizeACGT(seq string, k int) (StringSet, error) {
+	if k <= 1 {
+		return nil, errors.New("k must be an integer > 1")
+	}
+	kmers := StringSet{}
+	run := 0 // number of consecutive ACGT bases ending at i
+	for i := 0; i < len(seq); i++ {
+		if _, ok := baseCodes[seq[i]]; !ok {
+			run = 0
+			continue
+		}
+		if run++; run < k {
+			continue
+		}
+		canonical, err := Canonize(seq[i-k+1 : i+1])
+		if err != nil {
+			return nil, err
+		}
+		kmers.Add(canonical)
+	}
+	return kmers, nil
+}
+
+// safeRatio returns num / den, or 0 if den is 0
+func safeRatio(num, den int) float64 {
+	if den == 0 {
+		return 0
+	}
+	return float64(num) / float64(den)
+}
+
+// countSymbols counts the occurrences of each symbol in a sequence
+func countSymbols(seq string) map[string]int {
+	counts := map[string]int{}
+	for _, char := range seq {
+		counts[string(char)]++
+	}
+	return counts
+}
+
+// shannonEntropy returns the entropy in bits of a symbol distribution
+func shannonEntropy(counts map[string]int) float64 {
+	total := 0
+	for _, count := range counts {
+		total += count
+	}
+	entropy := 0.
+	for _, count := range counts {
+		if count == 0 {
+			continue
+		}
+		p := float64(count) / float64(total)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
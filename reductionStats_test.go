@@ -0,0 +1,145 @@
+package reductions
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+var hpcSurjection = map[string]string{
+	"AA": ".", "AC": "C", "AG": "G", "AT": "T",
+	"CA": "A", "CC": ".", "CG": "G", "CT": "T",
+	"GA": "A", "GC": "C", "GG": ".", "GT": "T",
+	"TA": "A", "TC": "C", "TG": "G", "TT": ".",
+}
+
+func TestShannonEntropy(t *testing.T) {
+	tests := []struct {
+		name   string
+		counts map[string]int
+		wanted float64
+	}{
+		{name: "Empty", counts: map[string]int{}, wanted: 0},
+		{name: "Single", counts: map[string]int{"A": 10}, wanted: 0},
+		{name: "Uniform", counts: map[string]int{"A": 2, "C": 2, "G": 2, "T": 2}, wanted: 2},
+		{name: "Half", counts: map[string]int{"A": 5, "C": 5}, wanted: 1},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			if ans := shannonEntropy(testCase.counts); math.Abs(ans-testCase.wanted) > 1e-12 {
+				t.Errorf("wanted %v got %v", testCase.wanted, ans)
+			}
+		})
+	}
+}
+
+func TestComputeReductionStats(t *testing.T) {
+	sequences := map[string]string{
+		"seq1": "AAACCCGGGTTT",
+		"seq2": "ACGT",
+		"seq3": "",
+	}
+	stats, err := ComputeReductionStats(sequences, []string{"seq2", "seq1", "seq3"}, HomopolymerCompression, 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(stats.Reads) != 3 || stats.Reads[0].ID != "seq2" {
+		t.Fatalf("reads should be in the given order: %v", stats.Reads)
+	}
+	seq1 := stats.Reads[1]
+	if seq1.ReducedLength != 4 || seq1.LengthRatio != 4./12. || seq1.Entropy != 2 {
+		t.Errorf("unexpected stats for seq1: %+v", seq1)
+	}
+	// canonical k-mers: AAA=TTT, CCC=GGG, AAC=GTT, ACC=GGT, CCG=CGG and ACG=CGT
+	if seq1.RawKmers != 5 || seq1.ReducedKmers != 1 {
+		t.Errorf("unexpected k-mer counts for seq1: %+v", seq1)
+	}
+	if stats.Reads[2].LengthRatio != 0 || stats.Reads[2].RawKmers != 0 {
+		t.Errorf("empty read should have null stats: %+v", stats.Reads[2])
+	}
+
+	if stats.RawLength != 16 || stats.ReducedLength != 8 || stats.LengthRatio != 0.5 {
+		t.Errorf("unexpected aggregate lengths: %+v", stats)
+	}
+	if stats.SymbolCounts["A"] != 2 || stats.Entropy != 2 {
+		t.Errorf("unexpected aggregate symbols: %v, %v", stats.SymbolCounts, stats.Entropy)
+	}
+	if stats.RawKmers != 6 || stats.ReducedKmers != 1 || stats.Windows != 0 {
+		t.Errorf("unexpected aggregate k-mers or windows: %+v", stats)
+	}
+}
+
+func TestComputeSurjectionStats(t *testing.T) {
+	sequences := map[string]string{"seq1": "AAACCCGGGTTT", "seq2": "ACGT"}
+	stats, err := ComputeSurjectionStats(sequences, nil, hpcSurjection, 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stats.Reads[0].ID != "seq1" || stats.Reads[0].Windows != 11 || stats.Reads[0].DeletedWindows != 8 {
+		t.Errorf("unexpected window stats for seq1: %+v", stats.Reads[0])
+	}
+	if stats.Windows != 14 || stats.DeletedWindows != 8 || stats.DeletedWindowFraction != 8./14. {
+		t.Errorf("unexpected aggregate window stats: %+v", stats)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteReductionStatsJSON(&buf, stats); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	decoded := ReductionStats{}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("could not decode JSON: %v", err)
+	}
+	if decoded.DeletedWindows != 8 || len(decoded.Reads) != 2 {
+		t.Errorf("unexpected decoded stats: %+v", decoded)
+	}
+}
+
+func TestComputeSurjectionStatsUnknownWindows(t *testing.T) {
+	sequences := map[string]string{"seq1": "AAANNCCC", "seq2": "A"}
+	stats, err := ComputeSurjectionStats(sequences, nil, hpcSurjection, 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// windows AA AA CC CC are mapped to ".", AN NN NC are unknown
+	read := stats.Reads[0]
+	if read.Windows != 7 || read.DeletedWindows != 4 || read.UnknownWindows != 3 {
+		t.Errorf("unexpected window stats for seq1: %+v", read)
+	}
+	if reduced := MakeReductionFunction(hpcSurjection)(sequences["seq1"]); read.ReducedLength != len(reduced) {
+		t.Errorf("wanted reduced length %d, got %d", len(reduced), read.ReducedLength)
+	}
+	// only AAA and CCC are made of ACGT bases, the reduced read "A" is shorter than k
+	if read.RawKmers != 2 || read.ReducedKmers != 0 {
+		t.Errorf("unexpected k-mer counts for seq1: %+v", read)
+	}
+	if stats.Reads[1].Windows != 0 || stats.Reads[1].ReducedLength != 1 {
+		t.Errorf("unexpected stats for short read: %+v", stats.Reads[1])
+	}
+	if stats.DeletedWindowFraction != 4./7. || stats.UnknownWindowFraction != 3./7. {
+		t.Errorf("unexpected aggregate window stats: %+v", stats)
+	}
+
+	if _, err := ComputeSurjectionStats(sequences, nil, map[string]string{"AA": ".", "ACG": "G"}, 3); err == nil {
+		t.Errorf("expected an error for a mapping with keys of different lengths")
+	}
+}
+
+func TestComputeReductionStatsErrors(t *testing.T) {
+	if _, err := ComputeReductionStats(map[string]string{"seq1": "ACGT"}, []string{"seq2"}, Identity, 3); err == nil {
+		t.Errorf("expected an error for a missing read")
+	}
+	if _, err := ComputeReductionStats(map[string]string{"seq1": "ACGT"}, nil, Identity, 1); err == nil {
+		t.Errorf("expected an error for k <= 1")
+	}
+	stats, err := ComputeReductionStats(map[string]string{"seq1": "ACNGTAA"}, nil, Identity, 3)
+	if err != nil {
+		t.Fatalf("unexpected error for an unknown nucleotide: %v", err)
+	}
+	// GTA and TAA are the only k-mers without N
+	if stats.RawKmers != 2 || stats.ReducedKmers != 2 {
+		t.Errorf("unexpected k-mer counts: %+v", stats)
+	}
+}
@@ -1,6 +1,7 @@
 package reductions
 
 import (
+	"math"
 	"testing"
 )
 
@@ -120,3 +121,60 @@ func TestComputeFarTerms(t *testing.T) {
 		t.Errorf("wanted %v got %v", wanted, ans)
 	}
 }
+
+func TestObjectivePhiChecked(t *testing.T) {
+	closeSet := []DistanceRecord{
+		{Key1: "k1", Key2: "k2", RawDistance: 0.1, ReducedDistance: 0.2},
+		{Key1: "k3", Key2: "k4", RawDistance: 0.2, ReducedDistance: 0.4},
+		{Key1: "k5", Key2: "k6", Failed: true, RawDistance: 0.2, ReducedDistance: math.NaN()},
+	}
+	farSet := []DistanceRecord{
+		{Key1: "k7", Key2: "k8", RawDistance: 0.5, ReducedDistance: 0.5},
+		{Key1: "k9", Key2: "k10", RawDistance: 0.5, ReducedDistance: 1},
+		{Key1: "k11", Key2: "k12", RawDistance: 0, ReducedDistance: 0},
+		{Key1: "k13", Key2: "k14", RawDistance: math.NaN(), ReducedDistance: 0.5},
+	}
+
+	phi, diagnostics, err := ObjectivePhiChecked(closeSet, farSet)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wanted := ObjectivePhi(closeSet[:2], farSet[:2])
+	if math.Abs(phi.Phi-wanted.Phi) > 1e-12 || math.Abs(phi.Mu-wanted.Mu) > 1e-12 {
+		t.Errorf("wanted %v got %v", wanted, phi)
+	}
+	if diagnostics.ExcludedZeroRaw != 1 || diagnostics.ExcludedFailed != 2 {
+		t.Errorf("unexpected diagnostics %+v", diagnostics)
+	}
+}
+
+func TestObjectivePhiCheckedErrors(t *testing.T) {
+	valid := []DistanceRecord{{Key1: "k1", Key2: "k2", RawDistance: 0.5, ReducedDistance: 0.5}}
+	tests := []struct {
+		name             string
+		closeSet, farSet []DistanceRecord
+		wanted           error
+	}{
+		{name: "EmptyClose", closeSet: nil, farSet: valid, wanted: ErrEmptyCloseSet},
+		{name: "EmptyFar", closeSet: valid, farSet: nil, wanted: ErrEmptyFarSet},
+		{
+			name:     "ZeroRawFar",
+			closeSet: valid,
+			farSet:   []DistanceRecord{{Key1: "k1", Key2: "k2"}},
+			wanted:   ErrEmptyFarSet,
+		},
+		{
+			name:     "ZeroMu",
+			closeSet: valid,
+			farSet:   []DistanceRecord{{Key1: "k1", Key2: "k2", RawDistance: 0.5}},
+			wanted:   ErrZeroMu,
+		},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			if _, _, err := ObjectivePhiChecked(testCase.closeSet, testCase.farSet); err != testCase.wanted {
+				t.Errorf("wanted error %v, got %v", testCase.wanted, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,258 @@
+package reductions
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/hillbig/rsdic"
+)
+
+// SidecarException records a raw base outside of ACGT that cannot be recovered from
+// the reduced read
+type SidecarException struct {
+	Pos  int
+	Base byte
+}
+
+// Sidecar stores what a reduction removes from a read, so that Decode can restore it:
+//   - Kept is the bit vector of kept raw positions
+//   - Deleted holds the deleted bases packed on 2 bits (A=0, C=1, G=2, T=3)
+//   - Substituted has one bit per kept position, set if its reduced character differs
+//     from the raw base, and Substitutions holds these raw bases packed on 2 bits
+//   - Exceptions holds the raw bases outside of ACGT that are deleted or substituted,
+//     sorted by position
+//
+// Like CoordinateMap, it assumes every kept position produces exactly one character
+// of the reduced read.
+type Sidecar struct {
+	Kept          *rsdic.RSDic
+	Deleted       []byte
+	Substituted   *rsdic.RSDic
+	Substitutions []byte
+	Exceptions    []SidecarException
+}
+
+// packedLength returns the number of bytes needed to pack n bases on 2 bits
+func packedLength(n uint64) uint64 {
+	return (n + 3) / 4
+}
+
+// packBase writes the 2-bit code of a base at index i of packed bases, writing A for
+// bases outside of ACGT
+func packBase(packed []byte, i int, base byte) {
+	packed[i/4] |= byte(baseCodes[base]) << (2 * uint(i%4))
+}
+
+// unpackBase reads the base at index i of packed bases
+func unpackBase(packed []byte, i int) byte {
+	return "ACGT"[packed[i/4]>>(2*uint(i%4))&3]
+}
+
+// EncodeSidecar builds the sidecar of a read reduced with the bit vector of kept
+// positions returned by MakeReductionFunctionBitVector
+func EncodeSidecar(raw, reduced string, kept *rsdic.RSDic) (*Sidecar, error) {
+	if int(kept.Num()) != len(raw) || int(kept.OneNum()) != len(reduced) {
+		return nil, fmt.Errorf("bit vector of %d positions with %d kept does not match a raw read of length %d reduced to %d",
+			kept.Num(), kept.OneNum(), len(raw), len(reduced))
+	}
+	sidecar := &Sidecar{
+		Kept:        kept,
+		Deleted:     make([]byte, packedLength(kept.ZeroNum())),
+		Substituted: rsdic.New(),
+		Exceptions:  make([]SidecarException, 0),
+	}
+	substitutions := make([]byte, 0, len(reduced))
+	var reducedPos, deletedPos int
+	for i := 0; i < len(raw); i++ {
+		_, inACGT := baseCodes[raw[i]]
+		if kept.Bit(uint64(i)) {
+			substituted := reduced[reducedPos] != raw[i]
+			sidecar.Substituted.PushBack(substituted)
+			if substituted {
+				substitutions = append(substitutions, raw[i])
+				if !inACGT {
+					sidecar.Exceptions = append(sidecar.Exceptions, SidecarException{Pos: i, Base: raw[i]})
+				}
+			}
+			reducedPos++
+			continue
+		}
+		if !inACGT {
+			sidecar.Exceptions = append(sidecar.Exceptions, SidecarException{Pos: i, Base: raw[i]})
+		}
+		packBase(sidecar.Deleted, deletedPos, raw[i])
+		deletedPos++
+	}
+	sidecar.Substitutions = make([]byte, packedLength(uint64(len(substitutions))))
+	for i, base := range substitutions {
+		packBase(sidecar.Substitutions, i, base)
+	}
+	return sidecar, nil
+}
+
+// Decode restores the raw read from its reduced version and sidecar
+func Decode(reduced string, sidecar *Sidecar) (string, error) {
+	kept, substituted := sidecar.Kept, sidecar.Substituted
+	if int(kept.OneNum()) != len(reduced) || substituted.Num() != kept.OneNum() {
+		return "", fmt.Errorf("sidecar keeps %d positions but the reduced read has length %d", kept.OneNum(), len(reduced))
+	}
+	if uint64(len(sidecar.Deleted)) != packedLength(kept.ZeroNum()) {
+		return "", errors.New("sidecar has the wrong number of deleted bases")
+	}
+	if uint64(len(sidecar.Substitutions)) != packedLength(substituted.OneNum()) {
+		return "", errors.New("sidecar has the wrong number of substituted bases")
+	}
+	raw := make([]byte, kept.Num())
+	var reducedPos, deletedPos, substitutionPos int
+	for i := range raw {
+		if !kept.Bit(uint64(i)) {
+			raw[i] = unpackBase(sidecar.Deleted, deletedPos)
+			deletedPos++
+			continue
+		}
+		if substituted.Bit(uint64(reducedPos)) {
+			raw[i] = unpackBase(sidecar.Substitutions, substitutionPos)
+			substitutionPos++
+		} else {
+			raw[i] = reduced[reducedPos]
+		}
+		reducedPos++
+	}
+	for _, exception := range sidecar.Exceptions {
+		if exception.Pos < 0 || exception.Pos >= len(raw) {
+			return "", fmt.Errorf("sidecar exception at position %d: %w", exception.Pos, ErrPositionOutOfRange)
+		}
+		raw[exception.Pos] = exception.Base
+	}
+	return string(raw), nil
+}
+
+// MarshalBinary encodes the sidecar as the length prefixed kept bit vector, the packed
+// deleted bases, the length prefixed substituted bit vector, the packed substituted
+// bases and the exceptions as position deltas and bases
+func (sidecar *Sidecar) MarshalBinary() ([]byte, error) {
+	var buffer bytes.Buffer
+	varint := make([]byte, binary.MaxVarintLen64)
+	writeUvarint := func(x uint64) {
+		buffer.Write(varint[:binary.PutUvarint(varint, x)])
+	}
+	for _, section := range []struct {
+		bits   *rsdic.RSDic
+		packed []byte
+	}{{sidecar.Kept, sidecar.Deleted}, {sidecar.Substituted, sidecar.Substitutions}} {
+		bits, err := section.bits.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		writeUvarint(uint64(len(bits)))
+		buffer.Write(bits)
+		buffer.Write(section.packed)
+	}
+	writeUvarint(uint64(len(sidecar.Exceptions)))
+	last := 0
+	for _, exception := range sidecar.Exceptions {
+		if exception.Pos < last {
+			return nil, errors.New("sidecar exceptions are not sorted")
+		}
+		writeUvarint(uint64(exception.Pos - last))
+		buffer.WriteByte(exception.Base)
+		last = exception.Pos
+	}
+	return buffer.Bytes(), nil
+}
+
+// readBitVector reads a length prefixed bit vector
+func readBitVector(reader *bytes.Reader) (*rsdic.RSDic, error) {
+	size, err := binary.ReadUvarint(reader)
+	if err != nil {
+		return nil, err
+	}
+	if size > uint64(reader.Len()) {
+		return nil, errors.New("truncated sidecar")
+	}
+	data := make([]byte, size)
+	reader.Read(data)
+	bits := rsdic.New()
+	if err := bits.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return bits, nil
+}
+
+// readPacked reads n bases packed on 2 bits
+func readPacked(reader *bytes.Reader, n uint64) ([]byte, error) {
+	packed := make([]byte, packedLength(n))
+	if read, _ := reader.Read(packed); read != len(packed) {
+		return nil, errors.New("truncated sidecar")
+	}
+	return packed, nil
+}
+
+// UnmarshalBinary decodes a sidecar encoded by MarshalBinary
+func (sidecar *Sidecar) UnmarshalBinary(data []byte) error {
+	reader := bytes.NewReader(data)
+	kept, err := readBitVector(reader)
+	if err != nil {
+		return err
+	}
+	deleted, err := readPacked(reader, kept.ZeroNum())
+	if err != nil {
+		return err
+	}
+	substituted, err := readBitVector(reader)
+	if err != nil {
+		return err
+	}
+	substitutions, err := readPacked(reader, substituted.OneNum())
+	if err != nil {
+		return err
+	}
+
+	count, err := binary.ReadUvarint(reader)
+	if err != nil {
+		return err
+	}
+	exceptions := make([]SidecarException, 0)
+	pos := 0
+	for i := uint64(0); i < count; i++ {
+		delta, err := binary.ReadUvarint(reader)
+		if err != nil {
+			return err
+		}
+		base, err := reader.ReadByte()
+		if err != nil {
+			return err
+		}
+		pos += int(delta)
+		exceptions = append(exceptions, SidecarException{Pos: pos, Base: base})
+	}
+	if reader.Len() != 0 {
+		return errors.New("trailing data after sidecar")
+	}
+
+	sidecar.Kept, sidecar.Deleted, sidecar.Exceptions = kept, deleted, exceptions
+	sidecar.Substituted, sidecar.Substitutions = substituted, substitutions
+	return nil
+}
+
+// MakeReductionFunctionWithSidecar create a reduction function from a mapping that also
+// returns the sidecar needed to decode the reduced read. It returns an error if the
+// mapping is empty or if its keys have different lengths.
+func MakeReductionFunctionWithSidecar(surjection map[string]string, options ReductionOptions) (func(string) (string, *Sidecar, error), error) {
+	reduce, err := MakeReductionFunctionBitVectorWithOptions(surjection, options)
+	if err != nil {
+		return nil, err
+	}
+	return func(read string) (string, *Sidecar, error) {
+		reduced, kept, err := reduce(read)
+		if err != nil {
+			return "", nil, err
+		}
+		sidecar, err := EncodeSidecar(read, reduced, kept)
+		if err != nil {
+			return "", nil, err
+		}
+		return reduced, sidecar, nil
+	}, nil
+}
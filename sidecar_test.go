@@ -0,0 +1,127 @@
+package reductions
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestSidecarRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(11))
+	randomReduction, err := GetRandomReduction("ACGT", "ACGT", 3, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tests = []struct {
+		name       string
+		surjection map[string]string
+		options    ReductionOptions
+		read       string
+	}{
+		{name: "Empty", surjection: hpcSurjection, options: DefaultReductionOptions, read: ""},
+		{name: "Short", surjection: hpcSurjection, options: DefaultReductionOptions, read: "A"},
+		{name: "HPC", surjection: hpcSurjection, options: DefaultReductionOptions, read: randomSequence(rng, 500)},
+		{name: "Ambiguous", surjection: hpcSurjection, options: DefaultReductionOptions, read: "AANNNNacgtTTTGCCA"},
+		{name: "AmbiguousPassThrough", surjection: hpcSurjection, options: ReductionOptions{UnknownWindows: PassThrough}, read: "AANNNNacgtTTTGCCA"},
+		{name: "Random", surjection: randomReduction, options: DefaultReductionOptions, read: randomSequence(rng, 500)},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			reduce, err := MakeReductionFunctionWithSidecar(testCase.surjection, testCase.options)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			reduced, sidecar, err := reduce(testCase.read)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			decoded, err := Decode(reduced, sidecar)
+			if err != nil || decoded != testCase.read {
+				t.Errorf("%s, %v (got)\n%s (wanted)", decoded, err, testCase.read)
+			}
+
+			data, err := sidecar.MarshalBinary()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var unmarshalled Sidecar
+			if err := unmarshalled.UnmarshalBinary(data); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			decoded, err = Decode(reduced, &unmarshalled)
+			if err != nil || decoded != testCase.read {
+				t.Errorf("%s, %v (got)\n%s (wanted)", decoded, err, testCase.read)
+			}
+		})
+	}
+}
+
+func TestSidecarIsCompact(t *testing.T) {
+	read := "AAAAAAAACCCCCCCCGGGGGGGGTTTTTTTT"
+	reduce, err := MakeReductionFunctionWithSidecar(hpcSurjection, DefaultReductionOptions)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	reduced, sidecar, err := reduce(read)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if reduced != "ACGT" || len(sidecar.Deleted) != 7 || len(sidecar.Exceptions) != 0 {
+		t.Errorf("%s, %d deleted bytes, %d exceptions (got)\nACGT, 7, 0 (wanted)", reduced, len(sidecar.Deleted), len(sidecar.Exceptions))
+	}
+}
+
+func TestSidecarIsCompactWithSubstitutions(t *testing.T) {
+	rng := rand.New(rand.NewSource(19))
+	surjection, err := GetRandomReduction("ACGT", "ACGT.", 4, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reduce, err := MakeReductionFunctionWithSidecar(surjection, DefaultReductionOptions)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	read := randomSequence(rng, 10000)
+	reduced, sidecar, err := reduce(read)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := sidecar.MarshalBinary()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// every raw base is stored on at most 2 bits, plus the bit vectors
+	if limit := len(read)/4 + len(read)/2; len(data) > limit {
+		t.Errorf("sidecar of %d bytes for a read of length %d reduced to %d (wanted at most %d)", len(data), len(read), len(reduced), limit)
+	}
+	if len(sidecar.Exceptions) != 0 {
+		t.Errorf("%d exceptions for a read over ACGT (wanted 0)", len(sidecar.Exceptions))
+	}
+	if decoded, err := Decode(reduced, sidecar); err != nil || decoded != read {
+		t.Errorf("decoded read differs from the raw read (%v)", err)
+	}
+}
+
+func TestSidecarErrors(t *testing.T) {
+	_, bits := MakeReductionFunctionBitVector(hpcSurjection)("AACGT")
+	if _, err := EncodeSidecar("AACG", "ACG", bits); err == nil {
+		t.Error("Was expecting error when the raw read does not match the bit vector")
+	}
+	sidecar, err := EncodeSidecar("AACGT", "ACGT", bits)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := Decode("ACG", sidecar); err == nil {
+		t.Error("Was expecting error when the reduced read does not match the sidecar")
+	}
+
+	data, err := sidecar.MarshalBinary()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var unmarshalled Sidecar
+	for _, corrupted := range [][]byte{{}, data[:len(data)-1], append(append([]byte{}, data...), 0)} {
+		if err := unmarshalled.UnmarshalBinary(corrupted); err == nil {
+			t.Errorf("Was expecting error when unmarshalling %v", corrupted)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package reductions
+
+import (
+	"math"
+)
+
+// KahanSum is a compensated summation accumulator (Kahan-Babuska-Neumaier), which
+// keeps the rounding error bounded regardless of the number of terms
+type KahanSum struct {
+	sum, compensation float64
+}
+
+// Add adds a term to the sum
+func (k *KahanSum) Add(x float64) {
+	t := k.sum + x
+	if math.Abs(k.sum) >= math.Abs(x) {
+		k.compensation += (k.sum - t) + x
+	} else {
+		k.compensation += (x - t) + k.sum
+	}
+	k.sum = t
+}
+
+// Sum returns the compensated sum of all the added terms
+func (k KahanSum) Sum() float64 {
+	return k.sum + k.compensation
+}
+
+// WelfordAccumulator computes the mean and variance of a stream of values in a
+// single numerically stable pass
+type WelfordAccumulator struct {
+	n        int
+	mean, m2 float64
+}
+
+// Add adds a value to the accumulator
+func (w *WelfordAccumulator) Add(x float64) {
+	w.n++
+	delta := x - w.mean
+	w.mean += delta / float64(w.n)
+	w.m2 += delta * (x - w.mean)
+}
+
+// Merge adds all the values of another accumulator to this one
+func (w *WelfordAccumulator) Merge(other WelfordAccumulator) {
+	if other.n == 0 {
+		return
+	}
+	if w.n == 0 {
+		*w = other
+		return
+	}
+	n := w.n + other.n
+	delta := other.mean - w.mean
+	w.mean += delta * float64(other.n) / float64(n)
+	w.m2 += other.m2 + delta*delta*float64(w.n)*float64(other.n)/float64(n)
+	w.n = n
+}
+
+// Count returns the number of values added to the accumulator
+func (w WelfordAccumulator) Count() int {
+	return w.n
+}
+
+// Mean returns the mean of the values, or NaN if there are none
+func (w WelfordAccumulator) Mean() float64 {
+	if w.n == 0 {
+		return math.NaN()
+	}
+	return w.mean
+}
+
+// Variance returns the population variance of the values, or NaN if there are none
+func (w WelfordAccumulator) Variance() float64 {
+	if w.n == 0 {
+		return math.NaN()
+	}
+	return w.m2 / float64(w.n)
+}
+
+// PhiAccumulator computes the objective phi from a stream of distance records,
+// in constant memory. It follows the same rules as ObjectivePhiChecked.
+type PhiAccumulator struct {
+	closeSum    KahanSum
+	nClose      int
+	farRatios   WelfordAccumulator
+	diagnostics PhiDiagnostics
+}
+
+// AddClose adds a record of the close set
+func (acc *PhiAccumulator) AddClose(record DistanceRecord) {
+	if isFailedRecord(record) {
+		acc.diagnostics.ExcludedFailed++
+		return
+	}
+	acc.closeSum.Add(record.ReducedDistance)
+	acc.nClose++
+}
+
+// AddFar adds a record of the far set
+func (acc *PhiAccumulator) AddFar(record DistanceRecord) {
+	if isFailedRecord(record) {
+		acc.diagnostics.ExcludedFailed++
+		return
+	}
+	if record.RawDistance == 0 {
+		acc.diagnostics.ExcludedZeroRaw++
+		return
+	}
+	acc.farRatios.Add(record.ReducedDistance / record.RawDistance)
+}
+
+// Merge adds all the records of another accumulator to this one
+func (acc *PhiAccumulator) Merge(other PhiAccumulator) {
+	acc.closeSum.Add(other.closeSum.sum)
+	acc.closeSum.Add(other.closeSum.compensation)
+	acc.nClose += other.nClose
+	acc.farRatios.Merge(other.farRatios)
+	acc.diagnostics.ExcludedFailed += other.diagnostics.ExcludedFailed
+	acc.diagnostics.ExcludedZeroRaw += other.diagnostics.ExcludedZeroRaw
+}
+
+// Result returns the objective computed on all the records added so far
+func (acc *PhiAccumulator) Result() (PhiRecord, PhiDiagnostics, error) {
+	if acc.nClose == 0 {
+		return PhiRecord{}, acc.diagnostics, ErrEmptyCloseSet
+	}
+	if acc.farRatios.Count() == 0 {
+		return PhiRecord{}, acc.diagnostics, ErrEmptyFarSet
+	}
+	mu := acc.farRatios.Mean()
+	if mu == 0 {
+		return PhiRecord{}, acc.diagnostics, ErrZeroMu
+	}
+	C := acc.closeSum.Sum() / float64(acc.nClose)
+	F := acc.farRatios.Variance()
+	return PhiRecord{
+		Phi: C + F/mu,
+		C:   C,
+		F:   F,
+		Mu:  mu,
+	}, acc.diagnostics, nil
+}
+
+// StreamObjectivePhi computes phi from the records received on a channel until it
+// is closed, without storing them. isClose assigns each record to the close or far set.
+func StreamObjectivePhi(records <-chan DistanceRecord, isClose func(DistanceRecord) bool) (PhiRecord, PhiDiagnostics, error) {
+	acc := PhiAccumulator{}
+	for record := range records {
+		if isClose(record) {
+			acc.AddClose(record)
+		} else {
+			acc.AddFar(record)
+		}
+	}
+	return acc.Result()
+}
@@ -0,0 +1,93 @@
+package reductions
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestKahanSum(t *testing.T) {
+	sum := KahanSum{}
+	sum.Add(1)
+	for i := 0; i < 1000000; i++ {
+		sum.Add(1e-16)
+	}
+	if wanted := 1 + 1e-10; math.Abs(sum.Sum()-wanted) > 1e-15 {
+		t.Errorf("wanted %v got %v", wanted, sum.Sum())
+	}
+
+	cancelling := KahanSum{}
+	for _, x := range []float64{1, 1e100, 1, -1e100} {
+		cancelling.Add(x)
+	}
+	if cancelling.Sum() != 2 {
+		t.Errorf("wanted 2 got %v", cancelling.Sum())
+	}
+}
+
+func TestWelfordAccumulator(t *testing.T) {
+	empty := WelfordAccumulator{}
+	if !math.IsNaN(empty.Mean()) || !math.IsNaN(empty.Variance()) {
+		t.Errorf("an empty accumulator should have NaN statistics")
+	}
+
+	values := []float64{2, 4, 4, 4, 5, 5, 7, 9}
+	acc, first, second := WelfordAccumulator{}, WelfordAccumulator{}, WelfordAccumulator{}
+	for i, value := range values {
+		acc.Add(value)
+		if i < 3 {
+			first.Add(value)
+		} else {
+			second.Add(value)
+		}
+	}
+	first.Merge(second)
+
+	for _, w := range []WelfordAccumulator{acc, first} {
+		if w.Count() != 8 || math.Abs(w.Mean()-5) > 1e-12 || math.Abs(w.Variance()-4) > 1e-12 {
+			t.Errorf("wanted n=8, mean=5, variance=4, got %d %v %v", w.Count(), w.Mean(), w.Variance())
+		}
+	}
+}
+
+func TestWelfordAccumulatorLargeOffset(t *testing.T) {
+	acc := WelfordAccumulator{}
+	for _, value := range []float64{1e9 + 4, 1e9 + 7, 1e9 + 13, 1e9 + 16} {
+		acc.Add(value)
+	}
+	if math.Abs(acc.Variance()-22.5) > 1e-6 {
+		t.Errorf("wanted variance 22.5, got %v", acc.Variance())
+	}
+}
+
+func TestStreamObjectivePhi(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	distances := makeBootstrapDistances(rng, 20, 0.8)
+	distances = append(distances, DistanceRecord{Key1: "a", Key2: "b", RawDistance: 0.9, ReducedDistance: math.NaN(), Failed: true})
+
+	records := make(chan DistanceRecord)
+	go func() {
+		for _, record := range distances {
+			records <- record
+		}
+		close(records)
+	}()
+	streamed, diagnostics, err := StreamObjectivePhi(records, RadiusCloseness(0.4))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	wanted, _, _ := ObjectivePhiChecked(MakeSequenceSets(distances, 0.4))
+	if math.Abs(streamed.Phi-wanted.Phi) > 1e-12 || math.Abs(streamed.F-wanted.F) > 1e-12 {
+		t.Errorf("wanted %v got %v", wanted, streamed)
+	}
+	if diagnostics.ExcludedFailed != 1 {
+		t.Errorf("expected 1 excluded failed record, got %+v", diagnostics)
+	}
+
+	empty := make(chan DistanceRecord)
+	close(empty)
+	if _, _, err := StreamObjectivePhi(empty, IsWFAClose); err != ErrEmptyCloseSet {
+		t.Errorf("wanted %v got %v", ErrEmptyCloseSet, err)
+	}
+}
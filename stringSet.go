@@ -1,6 +1,7 @@
 package reductions
 
 import (
+	"encoding/json"
 	"fmt"
 	"sort"
 	"strings"
@@ -11,13 +12,23 @@ type StringSet map[string]bool
 
 // MakeSet makes a set out of a slice of strings
 func MakeSet(sequences []string) StringSet {
-	set := StringSet{}
+	set := make(StringSet, len(sequences))
 	for _, seq := range sequences {
 		set[seq] = true
 	}
 	return set
 }
 
+// Add inserts an element in the set
+func (set1 StringSet) Add(key string) {
+	set1[key] = true
+}
+
+// Contains returns true if the element is in the set
+func (set1 StringSet) Contains(key string) bool {
+	return set1[key]
+}
+
 // Intersection returns the intersection of 2 sets of strings
 func (set1 StringSet) Intersection(set2 StringSet) StringSet {
 	intersection := StringSet{}
@@ -29,6 +40,26 @@ func (set1 StringSet) Intersection(set2 StringSet) StringSet {
 	return intersection
 }
 
+// IntersectionLen returns the size of the intersection of 2 sets
+// without building the intersection, iterating over the smaller set
+func (set1 StringSet) IntersectionLen(set2 StringSet) int {
+	if len(set2) < len(set1) {
+		set1, set2 = set2, set1
+	}
+	count := 0
+	for key := range set1 {
+		if set2[key] {
+			count++
+		}
+	}
+	return count
+}
+
+// UnionLen returns the size of the union of 2 sets without building the union
+func (set1 StringSet) UnionLen(set2 StringSet) int {
+	return len(set1) + len(set2) - set1.IntersectionLen(set2)
+}
+
 // Union returns the union of 2 sets of strings
 func (set1 StringSet) Union(set2 StringSet) StringSet {
 	union := StringSet{}
@@ -41,6 +72,57 @@ func (set1 StringSet) Union(set2 StringSet) StringSet {
 	return union
 }
 
+// Difference returns the elements of set1 that are not in set2
+func (set1 StringSet) Difference(set2 StringSet) StringSet {
+	difference := StringSet{}
+	for key := range set1 {
+		if !set2[key] {
+			difference[key] = true
+		}
+	}
+	return difference
+}
+
+// SymmetricDifference returns the elements that are in exactly one of the 2 sets
+func (set1 StringSet) SymmetricDifference(set2 StringSet) StringSet {
+	difference := set1.Difference(set2)
+	for key := range set2 {
+		if !set1[key] {
+			difference[key] = true
+		}
+	}
+	return difference
+}
+
+// UnionInPlace adds all the elements of set2 to set1
+func (set1 StringSet) UnionInPlace(set2 StringSet) {
+	for key := range set2 {
+		set1[key] = true
+	}
+}
+
+// IntersectionInPlace removes the elements of set1 that are not in set2
+func (set1 StringSet) IntersectionInPlace(set2 StringSet) {
+	for key := range set1 {
+		if !set2[key] {
+			delete(set1, key)
+		}
+	}
+}
+
+// IsSubset returns true if all the elements of set1 are in set2
+func (set1 StringSet) IsSubset(set2 StringSet) bool {
+	if len(set1) > len(set2) {
+		return false
+	}
+	for key := range set1 {
+		if !set2[key] {
+			return false
+		}
+	}
+	return true
+}
+
 // IsEqual returns true if the 2 sets are equal and false if not
 func (set1 StringSet) IsEqual(set2 StringSet) bool {
 	if len(set1) != len(set2) {
@@ -56,21 +138,39 @@ func (set1 StringSet) IsEqual(set2 StringSet) bool {
 	return true
 }
 
+// Sorted returns the elements of the set in lexicographical order
+func (set1 StringSet) Sorted() []string {
+	keys := make([]string, 0, len(set1))
+	for k := range set1 {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // String returns a string readable string representation of a set
 func (set1 StringSet) String() string {
 	var sb strings.Builder
 	sb.WriteString("Set{")
-	keys := make([]string, len(set1))
-	i := 0
-	for k := range set1 {
-		keys[i] = k
-		i++
-	}
-	sort.Strings(keys)
-	for _, key := range keys {
+	for _, key := range set1.Sorted() {
 		sb.WriteString(key + ",")
 	}
 
 	sb.WriteString(fmt.Sprintf("(%d)}", len(set1)))
 	return sb.String()
 }
+
+// MarshalJSON encodes the set as a sorted JSON array
+func (set1 StringSet) MarshalJSON() ([]byte, error) {
+	return json.Marshal(set1.Sorted())
+}
+
+// UnmarshalJSON decodes a JSON array into the set
+func (set1 *StringSet) UnmarshalJSON(data []byte) error {
+	var keys []string
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return err
+	}
+	*set1 = MakeSet(keys)
+	return nil
+}
@@ -1,6 +1,7 @@
 package reductions
 
 import (
+	"encoding/json"
 	"fmt"
 	"testing"
 )
@@ -168,6 +169,122 @@ func TestStringSet_String(t *testing.T) {
 	}
 }
 
+func TestStringSet_IntersectionLen(t *testing.T) {
+	set := MakeSet([]string{"ATG", "GAC", "GCC"})
+	var tests = []struct {
+		name       string
+		set1, set2 StringSet
+		wanted     int
+	}{
+		{name: "EmptySet", set1: set, set2: StringSet{}, wanted: 0},
+		{name: "Subsets", set1: set, set2: MakeSet([]string{"ATG", "GAC", "GCA", "GGG"}), wanted: 2},
+		{name: "DisjointSets", set1: set, set2: MakeSet([]string{"GCA", "GGG", "TTT"}), wanted: 0},
+		{name: "SameSet", set1: set, set2: set, wanted: 3},
+		{name: "SmallerFirst", set1: MakeSet([]string{"ATG"}), set2: set, wanted: 1},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			if ans := testCase.set1.IntersectionLen(testCase.set2); ans != testCase.wanted {
+				t.Errorf("wanted %d, got %d", testCase.wanted, ans)
+			}
+			if ans := testCase.set2.IntersectionLen(testCase.set1); ans != testCase.wanted {
+				t.Errorf("wanted %d, got %d (reversed)", testCase.wanted, ans)
+			}
+			wantedUnion := len(testCase.set1.Union(testCase.set2))
+			if ans := testCase.set1.UnionLen(testCase.set2); ans != wantedUnion {
+				t.Errorf("wanted union length %d, got %d", wantedUnion, ans)
+			}
+		})
+	}
+}
+
+func TestStringSet_Difference(t *testing.T) {
+	set := MakeSet([]string{"ATG", "GAC", "GCC"})
+	var tests = []struct {
+		name                           string
+		set1, set2, wanted, wantedSymm StringSet
+	}{
+		{
+			name: "EmptySet", set1: set, set2: StringSet{},
+			wanted: set, wantedSymm: set,
+		},
+		{
+			name: "Overlapping", set1: set, set2: MakeSet([]string{"ATG", "GAC", "GCA"}),
+			wanted: MakeSet([]string{"GCC"}), wantedSymm: MakeSet([]string{"GCC", "GCA"}),
+		},
+		{
+			name: "SameSet", set1: set, set2: set,
+			wanted: StringSet{}, wantedSymm: StringSet{},
+		},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			ans := testCase.set1.Difference(testCase.set2)
+			if !ans.IsEqual(testCase.wanted) {
+				t.Errorf("%s not equal to %s", ans.String(), testCase.wanted.String())
+			}
+			ans = testCase.set1.SymmetricDifference(testCase.set2)
+			if !ans.IsEqual(testCase.wantedSymm) {
+				t.Errorf("%s not equal to %s", ans.String(), testCase.wantedSymm.String())
+			}
+		})
+	}
+}
+
+func TestStringSet_IsSubset(t *testing.T) {
+	set := MakeSet([]string{"ATG", "GAC", "GCC"})
+	var tests = []struct {
+		name       string
+		set1, set2 StringSet
+		wanted     bool
+	}{
+		{name: "EmptySubset", set1: StringSet{}, set2: set, wanted: true},
+		{name: "SameSet", set1: set, set2: set, wanted: true},
+		{name: "StrictSubset", set1: MakeSet([]string{"ATG"}), set2: set, wanted: true},
+		{name: "Superset", set1: set, set2: MakeSet([]string{"ATG"}), wanted: false},
+		{name: "Disjoint", set1: MakeSet([]string{"TTT"}), set2: set, wanted: false},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			if ans := testCase.set1.IsSubset(testCase.set2); ans != testCase.wanted {
+				t.Errorf("got subset=%v, wanted %v", ans, testCase.wanted)
+			}
+		})
+	}
+}
+
+func TestStringSet_InPlace(t *testing.T) {
+	set := MakeSet([]string{"ATG", "GAC", "GCC"})
+	set.UnionInPlace(MakeSet([]string{"GCC", "TTT"}))
+	wanted := MakeSet([]string{"ATG", "GAC", "GCC", "TTT"})
+	if !set.IsEqual(wanted) {
+		t.Errorf("%s not equal to %s", set.String(), wanted.String())
+	}
+	set.IntersectionInPlace(MakeSet([]string{"ATG", "TTT", "AAA"}))
+	wanted = MakeSet([]string{"ATG", "TTT"})
+	if !set.IsEqual(wanted) {
+		t.Errorf("%s not equal to %s", set.String(), wanted.String())
+	}
+}
+
+func TestStringSet_JSON(t *testing.T) {
+	set := MakeSet([]string{"TTT", "AAA", "GGG"})
+	encoded, err := json.Marshal(set)
+	if err != nil {
+		t.Fatalf("error marshalling set: %v", err)
+	}
+	if string(encoded) != `["AAA","GGG","TTT"]` {
+		t.Errorf("unexpected encoding: %s", encoded)
+	}
+	decoded := StringSet{}
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatalf("error unmarshalling set: %v", err)
+	}
+	if !decoded.IsEqual(set) {
+		t.Errorf("%s not equal to %s", decoded.String(), set.String())
+	}
+}
+
 func BenchmarkStringSet_Intersection(b *testing.B) {
 	seqs, _, _ := ParseFasta("test.fasta")
 	kmers1, _ := Kmerize(seqs["Seq01"], 5)
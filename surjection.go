@@ -2,13 +2,11 @@ package reductions
 
 import (
 	"errors"
-	"fmt"
 	"github.com/hillbig/rsdic"
 	"gonum.org/v1/gonum/stat/combin"
 	"math"
 	"math/rand"
 	"sort"
-	"strings"
 	"time"
 )
 
@@ -97,147 +95,76 @@ func GetRandomReduction(inputAlphabet, outputAlphabet string, inputSize, outputS
 	return function, nil
 }
 
-// MakeReductionFunction create a reduction function from a mapping
+// MakeReductionFunction create a reduction function from a mapping. Mappings over
+// ACGT are compiled to a SurjectionTable, other mappings are applied with map lookups.
+// Reads shorter than the order are returned unchanged and unknown windows are deleted,
+// see MakeReductionFunctionWithOptions to change this. It panics if the mapping is
+// empty or if its keys have different lengths.
 func MakeReductionFunction(surjection map[string]string) func(string) string {
+	reduce, err := MakeReductionFunctionWithOptions(surjection, DefaultReductionOptions)
+	if err != nil {
+		panic(err)
+	}
 	return func(read string) string {
-		var order int
-		for k := range surjection {
-			order = len(k)
-			break
-		}
-
-		var builder strings.Builder
-		builder.WriteString(read[0 : order-1])
-		for i := 0; i <= len(read)-order; i++ {
-			s := surjection[read[i:i+order]]
-			if s == "." {
-				continue
-			}
-			builder.WriteString(s)
-		}
-		return builder.String()
+		reduced, _ := reduce(read)
+		return reduced
 	}
 }
 
-// MakeReductionFunctionDeleteAmbs create a reduction function from a mapping
+// MakeReductionFunctionDeleteAmbs create a reduction function from a mapping, deleting
+// unknown windows. It is the same as MakeReductionFunction, and panics if the mapping
+// is empty or if its keys have different lengths.
 func MakeReductionFunctionDeleteAmbs(surjection map[string]string) func(string) string {
+	options := DefaultReductionOptions
+	options.UnknownWindows = Drop
+	reduce, err := MakeReductionFunctionWithOptions(surjection, options)
+	if err != nil {
+		panic(err)
+	}
 	return func(read string) string {
-		var order int
-		for k := range surjection {
-			order = len(k)
-			break
-		}
-
-		var builder strings.Builder
-		builder.WriteString(read[0 : order-1])
-		for i := 0; i <= len(read)-order; i++ {
-			s := surjection[read[i:i+order]]
-			if s == "." || s == "" {
-				continue
-			}
-			builder.WriteString(s)
-		}
-		return builder.String()
+		reduced, _ := reduce(read)
+		return reduced
 	}
 }
 
-// MakeReductionFunctionKeepOffsets create a reduction function from a mapping
+// MakeReductionFunctionKeepOffsets create a reduction function from a mapping. It
+// panics if the mapping is empty or if its keys have different lengths.
 func MakeReductionFunctionKeepOffsets(surjection map[string]string) func(string) (string, string) {
+	reduce, err := MakeReductionFunctionKeepOffsetsWithOptions(surjection, DefaultReductionOptions)
+	if err != nil {
+		panic(err)
+	}
 	return func(read string) (string, string) {
-		var order int
-		for k := range surjection {
-			order = len(k)
-			break
-		}
-
-		var builder strings.Builder
-		encoded := ""
-		op, count := "M", order-1
-
-		builder.WriteString(read[0 : order-1])
-
-		for i := 0; i <= len(read)-order; i++ {
-			s := surjection[read[i:i+order]]
-			if s == "." {
-				if op == "M" {
-					encoded += fmt.Sprintf("M%d", count)
-					count = 0
-					op = "D"
-				}
-				count++
-				continue
-			}
-			if op == "D" {
-				encoded += fmt.Sprintf("D%d", count)
-				count = 0
-				op = "M"
-			}
-			count++
-			builder.WriteString(s)
-		}
-		encoded += fmt.Sprintf("%s%d", op, count)
-
-		return builder.String(), encoded
+		reduced, offsets, _ := reduce(read)
+		return reduced, offsets
 	}
 }
 
-
-// MakeReductionFunctionBitVector create a reduction function from a mapping
+// MakeReductionFunctionBitVector create a reduction function from a mapping. It
+// panics if the mapping is empty or if its keys have different lengths.
 func MakeReductionFunctionBitVector(surjection map[string]string) func(string) (string, *rsdic.RSDic) {
+	reduce, err := MakeReductionFunctionBitVectorWithOptions(surjection, DefaultReductionOptions)
+	if err != nil {
+		panic(err)
+	}
 	return func(read string) (string, *rsdic.RSDic) {
-		var order int
-		for k := range surjection {
-			order = len(k)
-			break
-		}
-		offsets := rsdic.New()
-
-		for i:=0; i<order-1; i++ {
-			offsets.PushBack(true)
-		}
-
-		var builder strings.Builder
-		builder.WriteString(read[0 : order-1])
-		for i := 0; i <= len(read)-order; i++ {
-			s := surjection[read[i:i+order]]
-			if s == "." {
-				offsets.PushBack(false)
-				continue
-			} else {
-				offsets.PushBack(true)
-			}
-			builder.WriteString(s)
-		}
-		return builder.String(), offsets
+		reduced, offsets, _ := reduce(read)
+		return reduced, offsets
 	}
 }
 
-// MakeReductionFunctionBitVectoDeleteAmbs create a reduction function from a mapping
+// MakeReductionFunctionBitVectoDeleteAmbs create a reduction function from a mapping,
+// deleting unknown windows. It is the same as MakeReductionFunctionBitVector, and
+// panics if the mapping is empty or if its keys have different lengths.
 func MakeReductionFunctionBitVectorDeleteAmbs(surjection map[string]string) func(string) (string, *rsdic.RSDic) {
+	options := DefaultReductionOptions
+	options.UnknownWindows = Drop
+	reduce, err := MakeReductionFunctionBitVectorWithOptions(surjection, options)
+	if err != nil {
+		panic(err)
+	}
 	return func(read string) (string, *rsdic.RSDic) {
-		var order int
-		for k := range surjection {
-			order = len(k)
-			break
-		}
-		offsets := rsdic.New()
-
-		for i:=0; i<order-1; i++ {
-			offsets.PushBack(true)
-		}
-
-		var builder strings.Builder
-		builder.WriteString(read[0 : order-1])
-		for i := 0; i <= len(read)-order; i++ {
-			s := surjection[read[i:i+order]]
-			if s == "." || s == "" {
-				offsets.PushBack(false)
-				continue
-			} else {
-				offsets.PushBack(true)
-			}
-			builder.WriteString(s)
-		}
-		return builder.String(), offsets
+		reduced, offsets, _ := reduce(read)
+		return reduced, offsets
 	}
-}
\ No newline at end of file
+}
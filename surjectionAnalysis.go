@@ -0,0 +1,142 @@
+package reductions
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SurjectionOrder returns the length of the input k-mers of a mapping, and an
+// error if the mapping is empty or if its keys have different lengths
+func SurjectionOrder(surjection map[string]string) (int, error) {
+	if len(surjection) == 0 {
+		return 0, errors.New("the mapping is empty")
+	}
+	order := -1
+	for key := range surjection {
+		if order == -1 {
+			order = len(key)
+		} else if len(key) != order {
+			return 0, fmt.Errorf("inconsistent key lengths in mapping: %d and %d", order, len(key))
+		}
+	}
+	if order == 0 {
+		return 0, errors.New("the mapping has an empty key")
+	}
+	return order, nil
+}
+
+// SurjectionReport describes the properties of a mapping.
+//   - Complete is true if every input k-mer over the input alphabet is mapped
+//   - Surjective is true if every output tuple over the output alphabet is reached
+//   - Preimages gives the number of input k-mers mapped to each output
+//   - DeletedFraction is the fraction of input k-mers mapped to "."
+//   - RCSymmetricDeletions is true if a k-mer is mapped to "." exactly when its
+//     reverse complement is
+//   - RCConsistent is true if the output of the reverse complement of a k-mer is the
+//     reverse complement of its output ("." being its own reverse complement)
+//   - GeneralizesHPC is true if every k-mer ending with 2 identical characters is
+//     mapped to ".", so that the reduction deletes at least what homopolymer
+//     compression does
+type SurjectionReport struct {
+	Order                         int
+	InputAlphabet, OutputAlphabet string
+	Complete, Surjective          bool
+	MissingInputs, MissingOutputs []string
+	UnexpectedInputs              []string
+	UnexpectedOutputs             []string
+	Preimages                     map[string]int
+	DeletedFraction               float64
+	RCSymmetricDeletions          bool
+	RCConsistent                  bool
+	GeneralizesHPC                bool
+}
+
+// AnalyzeSurjection checks the properties of a mapping from k-mers over the input
+// alphabet to tuples of outputSize characters over the output alphabet (as made by
+// GetRandomReduction). It returns an error if the keys have different lengths.
+func AnalyzeSurjection(surjection map[string]string, inputAlphabet, outputAlphabet string, outputSize int) (SurjectionReport, error) {
+	order, err := SurjectionOrder(surjection)
+	if err != nil {
+		return SurjectionReport{}, err
+	}
+
+	report := SurjectionReport{
+		Order:                order,
+		InputAlphabet:        inputAlphabet,
+		OutputAlphabet:       outputAlphabet,
+		Preimages:            map[string]int{},
+		RCSymmetricDeletions: true,
+		RCConsistent:         true,
+		GeneralizesHPC:       order >= 2,
+	}
+
+	inputs := MakeSet(GetTuples(order, 0, inputAlphabet, []string{}))
+	outputs := MakeSet(GetTuples(outputSize, 0, outputAlphabet, []string{}))
+
+	deleted := 0
+	for input, output := range surjection {
+		if !inputs.Contains(input) {
+			report.UnexpectedInputs = append(report.UnexpectedInputs, input)
+		}
+		if !outputs.Contains(output) && output != "." {
+			report.UnexpectedOutputs = append(report.UnexpectedOutputs, output)
+		}
+		report.Preimages[output]++
+		if output == "." {
+			deleted++
+		}
+
+		if order >= 2 && input[order-1] == input[order-2] && output != "." {
+			report.GeneralizesHPC = false
+		}
+
+		rc, err := ReverseComplement(input)
+		if err != nil {
+			report.RCSymmetricDeletions, report.RCConsistent = false, false
+			continue
+		}
+		rcOutput, ok := surjection[rc]
+		if !ok || (output == ".") != (rcOutput == ".") {
+			report.RCSymmetricDeletions = false
+		}
+		if !ok || rcOutput != reverseComplementOutput(output) {
+			report.RCConsistent = false
+		}
+	}
+
+	for input := range inputs {
+		if _, ok := surjection[input]; !ok {
+			report.MissingInputs = append(report.MissingInputs, input)
+		}
+	}
+	for output := range outputs {
+		if report.Preimages[output] == 0 {
+			report.MissingOutputs = append(report.MissingOutputs, output)
+		}
+	}
+	sort.Strings(report.MissingInputs)
+	sort.Strings(report.MissingOutputs)
+	sort.Strings(report.UnexpectedInputs)
+	sort.Strings(report.UnexpectedOutputs)
+
+	report.Complete = len(report.MissingInputs) == 0
+	report.Surjective = len(report.MissingOutputs) == 0
+	report.DeletedFraction = float64(deleted) / float64(len(surjection))
+	return report, nil
+}
+
+// reverseComplementOutput returns the reverse complement of an output, "." being
+// its own reverse complement. Outputs that cannot be reverse complemented are
+// returned unchanged.
+func reverseComplementOutput(output string) string {
+	if strings.Trim(output, ".") == "" {
+		return output
+	}
+	rc, err := ReverseComplement(output)
+	if err != nil {
+		return output
+	}
+	return rc
+}
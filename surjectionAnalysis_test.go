@@ -0,0 +1,93 @@
+package reductions
+
+import (
+	"testing"
+)
+
+func TestSurjectionOrder(t *testing.T) {
+	tests := []struct {
+		name       string
+		surjection map[string]string
+		wanted     int
+		fails      bool
+	}{
+		{name: "Order2", surjection: hpcSurjection, wanted: 2},
+		{name: "Empty", surjection: map[string]string{}, fails: true},
+		{name: "EmptyKey", surjection: map[string]string{"": "A"}, fails: true},
+		{name: "Inconsistent", surjection: map[string]string{"AA": "A", "AAA": "A"}, fails: true},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			order, err := SurjectionOrder(testCase.surjection)
+			if testCase.fails {
+				if err == nil {
+					t.Errorf("expected an error")
+				}
+				return
+			}
+			if err != nil || order != testCase.wanted {
+				t.Errorf("wanted %d got %d (%v)", testCase.wanted, order, err)
+			}
+		})
+	}
+}
+
+func TestAnalyzeSurjection(t *testing.T) {
+	report, err := AnalyzeSurjection(hpcSurjection, "ACGT", "ACGT.", 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.Order != 2 || !report.Complete || !report.Surjective {
+		t.Errorf("HPC mapping should be complete and surjective: %+v", report)
+	}
+	if report.Preimages["."] != 4 || report.Preimages["A"] != 3 || report.DeletedFraction != 0.25 {
+		t.Errorf("unexpected preimages: %v, %v", report.Preimages, report.DeletedFraction)
+	}
+	if !report.GeneralizesHPC || !report.RCSymmetricDeletions {
+		t.Errorf("HPC mapping should generalize HPC with symmetric deletions: %+v", report)
+	}
+	// AC -> C but GT -> T instead of G
+	if report.RCConsistent {
+		t.Errorf("last character mapping is not RC consistent")
+	}
+}
+
+func TestAnalyzeSurjectionProperties(t *testing.T) {
+	// the output of the reverse complement of a k-mer is the complement of its
+	// output, so palindromic k-mers have to be deleted
+	rcConsistent := map[string]string{
+		"AA": ".", "AC": "A", "AG": "C", "AT": ".",
+		"CA": "G", "CC": ".", "CG": ".", "CT": "G",
+		"GA": "C", "GC": ".", "GG": ".", "GT": "T",
+		"TA": ".", "TC": "G", "TG": "C", "TT": ".",
+	}
+	report, err := AnalyzeSurjection(rcConsistent, "ACGT", "ACGT.", 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !report.RCConsistent || !report.RCSymmetricDeletions {
+		t.Errorf("mapping should be RC consistent: %+v", report)
+	}
+
+	partial := map[string]string{"AA": "A", "AC": "A", "NA": "X", "GG": "A"}
+	report, err = AnalyzeSurjection(partial, "ACGT", "ACGT.", 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.Complete || len(report.MissingInputs) != 13 {
+		t.Errorf("expected 13 missing inputs, got %v", report.MissingInputs)
+	}
+	if report.Surjective || len(report.MissingOutputs) != 4 {
+		t.Errorf("expected 4 missing outputs, got %v", report.MissingOutputs)
+	}
+	if len(report.UnexpectedInputs) != 1 || len(report.UnexpectedOutputs) != 1 {
+		t.Errorf("expected NA -> X to be unexpected: %v %v", report.UnexpectedInputs, report.UnexpectedOutputs)
+	}
+	if report.GeneralizesHPC || report.RCConsistent || report.RCSymmetricDeletions {
+		t.Errorf("partial mapping should not have any property: %+v", report)
+	}
+
+	if _, err := AnalyzeSurjection(map[string]string{"A": "A", "AC": "A"}, "ACGT", "ACGT.", 1); err == nil {
+		t.Errorf("expected an error for inconsistent key lengths")
+	}
+}
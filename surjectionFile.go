@@ -0,0 +1,133 @@
+package reductions
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+)
+
+// SurjectionFileVersion is the version of the surjection file format written by WriteSurjectionFile.
+// Version 0 is the bare JSON object mapping input k-mers to outputs.
+const SurjectionFileVersion = 1
+
+// SurjectionMetadata records how a mapping was produced
+type SurjectionMetadata struct {
+	InputAlphabet  string     `json:"input_alphabet,omitempty"`
+	OutputAlphabet string     `json:"output_alphabet,omitempty"`
+	Order          int        `json:"order"`
+	OutputSize     int        `json:"output_size,omitempty"`
+	Seed           *int64     `json:"seed,omitempty"`
+	Objective      string     `json:"objective,omitempty"`
+	Score          *float64   `json:"score,omitempty"`
+	Dataset        string     `json:"dataset,omitempty"`
+	Generator      string     `json:"generator,omitempty"`
+	CreatedAt      *time.Time `json:"created_at,omitempty"`
+}
+
+// SurjectionFile is a mapping wrapped with its metadata
+type SurjectionFile struct {
+	Version  int                `json:"version"`
+	Metadata SurjectionMetadata `json:"metadata"`
+	Mapping  map[string]string  `json:"mapping"`
+}
+
+// NewSurjectionFile wraps a mapping with its metadata, filling in the order of the mapping
+func NewSurjectionFile(mapping map[string]string, metadata SurjectionMetadata) (SurjectionFile, error) {
+	order, err := SurjectionOrder(mapping)
+	if err != nil {
+		return SurjectionFile{}, err
+	}
+	metadata.Order = order
+	file := SurjectionFile{Version: SurjectionFileVersion, Metadata: metadata, Mapping: mapping}
+	return file, file.Validate()
+}
+
+// Validate checks that the mapping is consistent with its metadata
+func (file SurjectionFile) Validate() error {
+	if file.Version < 0 || file.Version > SurjectionFileVersion {
+		return fmt.Errorf("unsupported surjection file version %d", file.Version)
+	}
+	order, err := SurjectionOrder(file.Mapping)
+	if err != nil {
+		return err
+	}
+	if file.Metadata.Order != order {
+		return fmt.Errorf("mapping has order %d but metadata declares %d", order, file.Metadata.Order)
+	}
+	for input, output := range file.Mapping {
+		if alphabet := file.Metadata.InputAlphabet; alphabet != "" && strings.Trim(input, alphabet) != "" {
+			return fmt.Errorf("input %q is not over the input alphabet %q", input, alphabet)
+		}
+		if output == "." {
+			continue
+		}
+		if alphabet := file.Metadata.OutputAlphabet; alphabet != "" && strings.Trim(output, alphabet) != "" {
+			return fmt.Errorf("output %q of %q is not over the output alphabet %q", output, input, alphabet)
+		}
+		if size := file.Metadata.OutputSize; size != 0 && len(output) != size {
+			return fmt.Errorf("output %q of %q does not have the declared size %d", output, input, size)
+		}
+	}
+	return nil
+}
+
+// ParseSurjectionFile decodes a surjection file, either in the versioned format
+// or as a bare JSON mapping (returned as version 0), and validates it
+func ParseSurjectionFile(data []byte) (SurjectionFile, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return SurjectionFile{}, err
+	}
+
+	var file SurjectionFile
+	_, hasVersion := fields["version"]
+	_, hasMapping := fields["mapping"]
+	if hasVersion && hasMapping {
+		if err := json.Unmarshal(data, &file); err != nil {
+			return SurjectionFile{}, err
+		}
+	} else {
+		if err := json.Unmarshal(data, &file.Mapping); err != nil {
+			return SurjectionFile{}, err
+		}
+		order, err := SurjectionOrder(file.Mapping)
+		if err != nil {
+			return SurjectionFile{}, err
+		}
+		file.Metadata.Order = order
+	}
+	if file.Mapping == nil {
+		return SurjectionFile{}, errors.New("surjection file has no mapping")
+	}
+
+	return file, file.Validate()
+}
+
+// ReadSurjectionFile reads and validates a surjection file in the versioned or bare format
+func ReadSurjectionFile(path string) (SurjectionFile, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return SurjectionFile{}, err
+	}
+	file, err := ParseSurjectionFile(data)
+	if err != nil {
+		return SurjectionFile{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return file, nil
+}
+
+// WriteSurjectionFile validates a surjection file and writes it in the versioned format
+func WriteSurjectionFile(path string, file SurjectionFile) error {
+	file.Version = SurjectionFileVersion
+	if err := file.Validate(); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
@@ -0,0 +1,110 @@
+package reductions
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSurjectionFileRoundTrip(t *testing.T) {
+	seed := int64(42)
+	score := 0.25
+	created := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	file, err := NewSurjectionFile(hpcSurjection, SurjectionMetadata{
+		InputAlphabet:  "ACGT",
+		OutputAlphabet: "ACGT",
+		OutputSize:     1,
+		Seed:           &seed,
+		Objective:      "phi",
+		Score:          &score,
+		Dataset:        "test",
+		Generator:      "unit-test",
+		CreatedAt:      &created,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if file.Metadata.Order != 2 {
+		t.Errorf("%d (got order)\n2 (wanted)", file.Metadata.Order)
+	}
+
+	path := filepath.Join(t.TempDir(), "hpc.json")
+	if err := WriteSurjectionFile(path, file); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ans, err := ReadSurjectionFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ans.Version != SurjectionFileVersion {
+		t.Errorf("%d (got version)\n%d (wanted)", ans.Version, SurjectionFileVersion)
+	}
+	if *ans.Metadata.Seed != seed || *ans.Metadata.Score != score || !ans.Metadata.CreatedAt.Equal(created) {
+		t.Errorf("%+v (got metadata)\n%+v (wanted)", ans.Metadata, file.Metadata)
+	}
+	if len(ans.Mapping) != len(file.Mapping) {
+		t.Fatalf("%d (got mapping size)\n%d (wanted)", len(ans.Mapping), len(file.Mapping))
+	}
+	for input, output := range file.Mapping {
+		if ans.Mapping[input] != output {
+			t.Errorf("%s -> %s (got)\n%s -> %s (wanted)", input, ans.Mapping[input], input, output)
+		}
+	}
+
+	var legacy map[string]string
+	if err := CheckSurjectionFile(path, &legacy); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(legacy) != len(file.Mapping) {
+		t.Errorf("%d (got mapping size)\n%d (wanted)", len(legacy), len(file.Mapping))
+	}
+}
+
+func TestParseSurjectionFileBare(t *testing.T) {
+	file, err := ParseSurjectionFile([]byte(`{"AA": ".", "AC": "C", "CA": "A", "CC": "."}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if file.Version != 0 || file.Metadata.Order != 2 || file.Mapping["AC"] != "C" {
+		t.Errorf("%+v (got)", file)
+	}
+}
+
+func TestParseSurjectionFileErrors(t *testing.T) {
+	var tests = []struct {
+		name, data string
+	}{
+		{name: "NotJSON", data: `not json`},
+		{name: "Empty", data: `{}`},
+		{name: "InconsistentOrder", data: `{"AA": "A", "ACG": "C"}`},
+		{name: "FutureVersion", data: `{"version": 99, "metadata": {"order": 1}, "mapping": {"A": "A"}}`},
+		{name: "WrongOrder", data: `{"version": 1, "metadata": {"order": 3}, "mapping": {"AA": "A"}}`},
+		{name: "NullMapping", data: `{"version": 1, "metadata": {"order": 1}, "mapping": null}`},
+		{name: "InputAlphabet", data: `{"version": 1, "metadata": {"order": 2, "input_alphabet": "ACGT"}, "mapping": {"AN": "A"}}`},
+		{name: "OutputAlphabet", data: `{"version": 1, "metadata": {"order": 2, "output_alphabet": "AC"}, "mapping": {"AT": "T"}}`},
+		{name: "OutputSize", data: `{"version": 1, "metadata": {"order": 2, "output_size": 1}, "mapping": {"AT": "AT"}}`},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := ParseSurjectionFile([]byte(testCase.data))
+			if err == nil {
+				t.Errorf("Was expecting error when parsing %s", testCase.data)
+			}
+		})
+	}
+}
+
+func TestReadSurjectionFileBareOnDisk(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bare.json")
+	if err := ioutil.WriteFile(path, []byte(`{"AC": "C", "CA": "A"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var mapping map[string]string
+	if err := CheckSurjectionFile(path, &mapping); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if mapping["CA"] != "A" {
+		t.Errorf("%v (got)", mapping)
+	}
+}
@@ -0,0 +1,269 @@
+package reductions
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// MaxTableOrder is the largest order a SurjectionTable can be compiled for (4^12 entries)
+const MaxTableOrder = 12
+
+// surjectionTableMagic starts every binary surjection table
+var surjectionTableMagic = [4]byte{'S', 'R', 'J', 'T'}
+
+// surjectionTableVersion is the version of the binary surjection table format
+const surjectionTableVersion = 1
+
+// SurjectionTable is a mapping over the ACGT alphabet compiled to a dense array.
+// The output of the input k-mer with 2-bit code i (A=0, C=1, G=2, T=3) is stored in
+// Width bytes at position i*Width, padded with zero bytes. Unmapped k-mers are all
+// zeros, deleted k-mers are stored as ".".
+type SurjectionTable struct {
+	Order, Width int
+	outputs      string
+	lengths      []uint8
+}
+
+// CompileSurjection builds a dense lookup table from a mapping whose keys are over ACGT
+func CompileSurjection(surjection map[string]string) (*SurjectionTable, error) {
+	order, err := SurjectionOrder(surjection)
+	if err != nil {
+		return nil, err
+	}
+	if order > MaxTableOrder {
+		return nil, fmt.Errorf("order %d is larger than the maximum table order %d", order, MaxTableOrder)
+	}
+	width := 0
+	for _, output := range surjection {
+		if len(output) > width {
+			width = len(output)
+		}
+	}
+	if width > 255 {
+		return nil, fmt.Errorf("outputs of length %d are too long for a table", width)
+	}
+
+	table := newSurjectionTable(order, width)
+	outputs := make([]byte, table.Len()*width)
+	for input, output := range surjection {
+		index, ok := kmerIndex(input)
+		if !ok {
+			return nil, fmt.Errorf("input %q is not over the ACGT alphabet", input)
+		}
+		if strings.IndexByte(output, 0) != -1 {
+			return nil, fmt.Errorf("output of %q contains a zero byte", input)
+		}
+		copy(outputs[index*width:], output)
+		table.lengths[index] = uint8(len(output))
+	}
+	table.outputs = string(outputs)
+	return table, nil
+}
+
+// newSurjectionTable allocates a table without outputs
+func newSurjectionTable(order, width int) *SurjectionTable {
+	return &SurjectionTable{
+		Order:   order,
+		Width:   width,
+		lengths: make([]uint8, 1<<(2*uint(order))),
+	}
+}
+
+// tableCodes gives the 2-bit code of each base, or -1 for bases outside of ACGT
+var tableCodes = func() (codes [256]int8) {
+	for i := range codes {
+		codes[i] = -1
+	}
+	for base, code := range baseCodes {
+		codes[base] = int8(code)
+	}
+	return codes
+}()
+
+// kmerIndex returns the 2-bit code of a k-mer, and false if it has a base outside of ACGT
+func kmerIndex(kmer string) (int, bool) {
+	index := 0
+	for i := 0; i < len(kmer); i++ {
+		code := tableCodes[kmer[i]]
+		if code < 0 {
+			return 0, false
+		}
+		index = index<<2 | int(code)
+	}
+	return index, true
+}
+
+// Len returns the number of entries of the table
+func (table *SurjectionTable) Len() int {
+	return len(table.lengths)
+}
+
+// Lookup returns the output of a k-mer, and false if it is not mapped
+func (table *SurjectionTable) Lookup(kmer string) (string, bool) {
+	if len(kmer) != table.Order {
+		return "", false
+	}
+	index, ok := kmerIndex(kmer)
+	if !ok || table.lengths[index] == 0 {
+		return "", false
+	}
+	return table.entry(index), true
+}
+
+// entry returns the output stored at an index without copying it
+func (table *SurjectionTable) entry(index int) string {
+	start := index * table.Width
+	return table.outputs[start : start+int(table.lengths[index])]
+}
+
+// Mapping converts the table back to a mapping, leaving out unmapped k-mers
+func (table *SurjectionTable) Mapping() map[string]string {
+	mapping := make(map[string]string)
+	kmer := make([]byte, table.Order)
+	for index := range table.lengths {
+		if table.lengths[index] == 0 {
+			continue
+		}
+		for i, code := 0, index; i < table.Order; i, code = i+1, code>>2 {
+			kmer[table.Order-1-i] = "ACGT"[code&3]
+		}
+		mapping[string(kmer)] = table.entry(index)
+	}
+	return mapping
+}
+
+// Reduce applies the table to a read like MakeReductionFunction, using a rolling
+// index instead of map lookups. Windows containing a base outside of ACGT are unmapped.
+func (table *SurjectionTable) Reduce(read string) string {
+	reduced, _ := table.reducer(DefaultReductionOptions).reduce(read)
+	return reduced
+}
+
+// reducer returns a reducer using the table
+func (table *SurjectionTable) reducer(options ReductionOptions) *reducer {
+	return &reducer{order: table.Order, table: table, options: options}
+}
+
+// MakeTableReductionFunction create a reduction function from a compiled table
+func MakeTableReductionFunction(table *SurjectionTable, options ReductionOptions) func(string) (string, error) {
+	return table.reducer(options).reduce
+}
+
+// WriteTo writes the table in the binary format: a 4 byte magic, the format version,
+// the order and the width as single bytes, followed by the dense output array
+func (table *SurjectionTable) WriteTo(w io.Writer) (int64, error) {
+	header := append(surjectionTableMagic[:], surjectionTableVersion, uint8(table.Order), uint8(table.Width))
+	n, err := w.Write(header)
+	if err != nil {
+		return int64(n), err
+	}
+	m, err := io.WriteString(w, table.outputs)
+	return int64(n + m), err
+}
+
+// ReadSurjectionTable reads a table in the binary format written by WriteTo
+func ReadSurjectionTable(r io.Reader) (*SurjectionTable, error) {
+	var header struct {
+		Magic                 [4]byte
+		Version, Order, Width uint8
+	}
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return nil, err
+	}
+	if header.Magic != surjectionTableMagic {
+		return nil, errors.New("not a binary surjection table")
+	}
+	if header.Version != surjectionTableVersion {
+		return nil, fmt.Errorf("unsupported surjection table version %d", header.Version)
+	}
+	if header.Order == 0 || header.Order > MaxTableOrder {
+		return nil, fmt.Errorf("invalid table order %d", header.Order)
+	}
+	if header.Width == 0 {
+		return nil, errors.New("invalid table width 0")
+	}
+
+	// the header is not trusted with the allocation of the outputs, which grow with
+	// the data actually read so that a truncated or corrupted table fails early
+	size := int64(1) << (2 * uint(header.Order)) * int64(header.Width)
+	var buffer bytes.Buffer
+	if n, err := io.CopyN(&buffer, r, size); err != nil {
+		if err == io.EOF {
+			err = fmt.Errorf("table is truncated after %d of %d bytes of outputs: %w", n, size, io.ErrUnexpectedEOF)
+		}
+		return nil, err
+	}
+	outputs := buffer.Bytes()
+
+	table := newSurjectionTable(int(header.Order), int(header.Width))
+	table.outputs = string(outputs)
+	for index := range table.lengths {
+		entry := outputs[index*table.Width : (index+1)*table.Width]
+		if length := bytes.IndexByte(entry, 0); length != -1 {
+			table.lengths[index] = uint8(length)
+		} else {
+			table.lengths[index] = uint8(table.Width)
+		}
+	}
+	return table, nil
+}
+
+// ReadSurjectionTableFile reads a binary surjection table from a file
+func ReadSurjectionTableFile(path string) (*SurjectionTable, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadSurjectionTable(bufio.NewReader(file))
+}
+
+// WriteSurjectionTableFile writes a binary surjection table to a file
+func WriteSurjectionTableFile(table *SurjectionTable, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(file)
+	if _, err := table.WriteTo(writer); err != nil {
+		file.Close()
+		return err
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// ConvertSurjectionJSONToBinary compiles a JSON surjection file to a binary table
+func ConvertSurjectionJSONToBinary(jsonPath, binaryPath string) error {
+	file, err := ReadSurjectionFile(jsonPath)
+	if err != nil {
+		return err
+	}
+	table, err := CompileSurjection(file.Mapping)
+	if err != nil {
+		return err
+	}
+	return WriteSurjectionTableFile(table, binaryPath)
+}
+
+// ConvertSurjectionBinaryToJSON writes a binary table as a versioned JSON surjection file
+func ConvertSurjectionBinaryToJSON(binaryPath, jsonPath string) error {
+	table, err := ReadSurjectionTableFile(binaryPath)
+	if err != nil {
+		return err
+	}
+	file, err := NewSurjectionFile(table.Mapping(), SurjectionMetadata{InputAlphabet: "ACGT"})
+	if err != nil {
+		return err
+	}
+	return WriteSurjectionFile(jsonPath, file)
+}
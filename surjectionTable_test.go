@@ -0,0 +1,187 @@
+package reductions
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestSurjectionTableReduce(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+	randomReduction, err := GetRandomReduction("ACGT", "ACGT", 4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	randomReduction["ACGT"] = "."
+	var tests = []struct {
+		name       string
+		surjection map[string]string
+	}{
+		{name: "HPC", surjection: hpcSurjection},
+		{name: "Random", surjection: randomReduction},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			table, err := CompileSurjection(testCase.surjection)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			reduce := mapReduction(t, testCase.surjection)
+			reads := []string{"AATTTGCCA", "GGNAAAACGTTN", randomSequence(rng, 500)}
+			for _, read := range reads {
+				if ans, wanted := table.Reduce(read), reduce(read); ans != wanted {
+					t.Errorf("%s (got)\n%s (wanted)", ans, wanted)
+				}
+			}
+		})
+	}
+}
+
+func TestSurjectionTableLookup(t *testing.T) {
+	table, err := CompileSurjection(map[string]string{"AC": "T", "CA": ".", "GG": "AC"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if table.Len() != 16 || table.Width != 2 {
+		t.Errorf("%d, %d (got len, width)\n16, 2 (wanted)", table.Len(), table.Width)
+	}
+	var tests = []struct {
+		kmer, wanted string
+		ok           bool
+	}{
+		{kmer: "AC", wanted: "T", ok: true},
+		{kmer: "CA", wanted: ".", ok: true},
+		{kmer: "GG", wanted: "AC", ok: true},
+		{kmer: "TT", wanted: "", ok: false},
+		{kmer: "NA", wanted: "", ok: false},
+		{kmer: "ACG", wanted: "", ok: false},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.kmer, func(t *testing.T) {
+			ans, ok := table.Lookup(testCase.kmer)
+			if ans != testCase.wanted || ok != testCase.ok {
+				t.Errorf("%q, %v (got)\n%q, %v (wanted)", ans, ok, testCase.wanted, testCase.ok)
+			}
+		})
+	}
+}
+
+func TestCompileSurjectionErrors(t *testing.T) {
+	var tests = []struct {
+		name       string
+		surjection map[string]string
+	}{
+		{name: "Empty", surjection: map[string]string{}},
+		{name: "NotACGT", surjection: map[string]string{"AN": "A"}},
+		{name: "TooLarge", surjection: map[string]string{"ACGTACGTACGTA": "A"}},
+		{name: "ZeroByte", surjection: map[string]string{"AC": "\x00"}},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := CompileSurjection(testCase.surjection)
+			if err == nil {
+				t.Errorf("Was expecting error when compiling %v", testCase.surjection)
+			}
+		})
+	}
+}
+
+func TestSurjectionTableBinaryRoundTrip(t *testing.T) {
+	mapping := map[string]string{"AC": "T", "CA": ".", "GG": "AC", "TT": "A"}
+	table, err := CompileSurjection(mapping)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var buffer bytes.Buffer
+	if _, err := table.WriteTo(&buffer); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if buffer.Len() != 7+16*2 {
+		t.Errorf("%d (got size)\n%d (wanted)", buffer.Len(), 7+16*2)
+	}
+	ans, err := ReadSurjectionTable(&buffer)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	decoded := ans.Mapping()
+	if len(decoded) != len(mapping) {
+		t.Fatalf("%v (got)\n%v (wanted)", decoded, mapping)
+	}
+	for input, output := range mapping {
+		if decoded[input] != output {
+			t.Errorf("%v (got)\n%v (wanted)", decoded, mapping)
+		}
+	}
+}
+
+func TestReadSurjectionTableErrors(t *testing.T) {
+	var tests = []struct {
+		name string
+		data []byte
+	}{
+		{name: "Empty", data: []byte{}},
+		{name: "BadMagic", data: []byte("JSON\x01\x01\x01AAAA")},
+		{name: "BadVersion", data: []byte("SRJT\x02\x01\x01AAAA")},
+		{name: "BadOrder", data: []byte("SRJT\x01\x00\x01")},
+		{name: "Truncated", data: []byte("SRJT\x01\x01\x01AA")},
+		{name: "NoWidth", data: []byte("SRJT\x01\x01\x00")},
+		{name: "HugeTruncated", data: []byte("SRJT\x01\x0c\xffAA")},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := ReadSurjectionTable(bytes.NewReader(testCase.data))
+			if err == nil {
+				t.Errorf("Was expecting error when reading %q", testCase.data)
+			}
+		})
+	}
+}
+
+func TestReadSurjectionTableDoesNotTrustHeader(t *testing.T) {
+	// the header announces 4^12 outputs of 255 bytes but the data is truncated
+	data := append([]byte("SRJT\x01\x0c\xff"), make([]byte, 1000)...)
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	_, err := ReadSurjectionTable(bytes.NewReader(data))
+	runtime.ReadMemStats(&after)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Was expecting io.ErrUnexpectedEOF, got %v", err)
+	}
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 1<<20 {
+		t.Errorf("%d bytes allocated for a truncated table", allocated)
+	}
+}
+
+func TestConvertSurjection(t *testing.T) {
+	dir := t.TempDir()
+	jsonPath := filepath.Join(dir, "hpc.json")
+	binaryPath := filepath.Join(dir, "hpc.bin")
+	roundTripPath := filepath.Join(dir, "roundtrip.json")
+
+	file, err := NewSurjectionFile(hpcSurjection, SurjectionMetadata{InputAlphabet: "ACGT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteSurjectionFile(jsonPath, file); err != nil {
+		t.Fatal(err)
+	}
+	if err := ConvertSurjectionJSONToBinary(jsonPath, binaryPath); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := ConvertSurjectionBinaryToJSON(binaryPath, roundTripPath); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ans, err := ReadSurjectionFile(roundTripPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for input, output := range hpcSurjection {
+		if ans.Mapping[input] != output {
+			t.Errorf("%s -> %s (got)\n%s -> %s (wanted)", input, ans.Mapping[input], input, output)
+		}
+	}
+}
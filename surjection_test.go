@@ -6,7 +6,9 @@ import (
 	"math/rand"
 	"reflect"
 	"sort"
+	"strings"
 	"testing"
+	"time"
 	"github.com/hillbig/rsdic"
 )
 
@@ -221,6 +223,14 @@ func TestMakeReductionFunction(t *testing.T) {
 			input:  "AGTCAGA",
 			wanted: "AAGTCAG",
 		},
+		{
+			name: "not ACGT",
+			reduction: map[string]string{
+				"AA": ".", "AN": "N", "NA": "A", "NN": ".",
+			},
+			input:  "AANNNA",
+			wanted: "ANA",
+		},
 	}
 
 	for _, testCase := range cases {
@@ -413,3 +423,59 @@ func TestMakeReductionFunctionBitVector(t *testing.T) {
 		})
 	}
 }
+
+// benchmarkReads returns reads with lengths typical of long read sequencing
+func benchmarkReads(n, length int) []string {
+	rng := rand.New(rand.NewSource(1))
+	reads := make([]string, n)
+	for i := range reads {
+		reads[i] = randomSequence(rng, length)
+	}
+	return reads
+}
+
+func benchmarkReduction(b *testing.B, reduce func(string) string) {
+	reads := benchmarkReads(16, 20000)
+	b.SetBytes(20000)
+	b.ResetTimer()
+	start := time.Now()
+	for i := 0; i < b.N; i++ {
+		reduce(reads[i%len(reads)])
+	}
+	b.ReportMetric(float64(b.N)/time.Since(start).Seconds(), "reads/s")
+}
+
+// mapReduction applies a mapping with map lookups, as MakeReductionFunction does without a table
+func mapReduction(tb testing.TB, surjection map[string]string) func(string) string {
+	order, err := SurjectionOrder(surjection)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return func(read string) string {
+		var builder strings.Builder
+		builder.WriteString(read[0 : order-1])
+		for i := 0; i <= len(read)-order; i++ {
+			s := surjection[read[i:i+order]]
+			if s == "." {
+				continue
+			}
+			builder.WriteString(s)
+		}
+		return builder.String()
+	}
+}
+
+func BenchmarkMakeReductionFunction(b *testing.B) {
+	for _, order := range []int{2, 5, 8} {
+		reduction, err := GetRandomReduction("ACGT", "ACGT", order, 1)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(fmt.Sprintf("Table/order%d", order), func(b *testing.B) {
+			benchmarkReduction(b, MakeReductionFunction(reduction))
+		})
+		b.Run(fmt.Sprintf("Map/order%d", order), func(b *testing.B) {
+			benchmarkReduction(b, mapReduction(b, reduction))
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package reductions
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Uint64Set is a set of unique integers, used to store encoded k-mers
+type Uint64Set map[uint64]bool
+
+// MakeUint64Set makes a set out of a slice of integers
+func MakeUint64Set(values []uint64) Uint64Set {
+	set := make(Uint64Set, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+	return set
+}
+
+// Add inserts an element in the set
+func (set1 Uint64Set) Add(key uint64) {
+	set1[key] = true
+}
+
+// Contains returns true if the element is in the set
+func (set1 Uint64Set) Contains(key uint64) bool {
+	return set1[key]
+}
+
+// Intersection returns the intersection of 2 sets of integers
+func (set1 Uint64Set) Intersection(set2 Uint64Set) Uint64Set {
+	intersection := Uint64Set{}
+	for key := range set1 {
+		if set2[key] {
+			intersection[key] = true
+		}
+	}
+	return intersection
+}
+
+// IntersectionLen returns the size of the intersection of 2 sets
+// without building the intersection, iterating over the smaller set
+func (set1 Uint64Set) IntersectionLen(set2 Uint64Set) int {
+	if len(set2) < len(set1) {
+		set1, set2 = set2, set1
+	}
+	count := 0
+	for key := range set1 {
+		if set2[key] {
+			count++
+		}
+	}
+	return count
+}
+
+// UnionLen returns the size of the union of 2 sets without building the union
+func (set1 Uint64Set) UnionLen(set2 Uint64Set) int {
+	return len(set1) + len(set2) - set1.IntersectionLen(set2)
+}
+
+// Union returns the union of 2 sets of integers
+func (set1 Uint64Set) Union(set2 Uint64Set) Uint64Set {
+	union := Uint64Set{}
+	for key := range set1 {
+		union[key] = true
+	}
+	for key := range set2 {
+		union[key] = true
+	}
+	return union
+}
+
+// Difference returns the elements of set1 that are not in set2
+func (set1 Uint64Set) Difference(set2 Uint64Set) Uint64Set {
+	difference := Uint64Set{}
+	for key := range set1 {
+		if !set2[key] {
+			difference[key] = true
+		}
+	}
+	return difference
+}
+
+// SymmetricDifference returns the elements that are in exactly one of the 2 sets
+func (set1 Uint64Set) SymmetricDifference(set2 Uint64Set) Uint64Set {
+	difference := set1.Difference(set2)
+	for key := range set2 {
+		if !set1[key] {
+			difference[key] = true
+		}
+	}
+	return difference
+}
+
+// UnionInPlace adds all the elements of set2 to set1
+func (set1 Uint64Set) UnionInPlace(set2 Uint64Set) {
+	for key := range set2 {
+		set1[key] = true
+	}
+}
+
+// IntersectionInPlace removes the elements of set1 that are not in set2
+func (set1 Uint64Set) IntersectionInPlace(set2 Uint64Set) {
+	for key := range set1 {
+		if !set2[key] {
+			delete(set1, key)
+		}
+	}
+}
+
+// IsSubset returns true if all the elements of set1 are in set2
+func (set1 Uint64Set) IsSubset(set2 Uint64Set) bool {
+	if len(set1) > len(set2) {
+		return false
+	}
+	for key := range set1 {
+		if !set2[key] {
+			return false
+		}
+	}
+	return true
+}
+
+// IsEqual returns true if the 2 sets are equal and false if not
+func (set1 Uint64Set) IsEqual(set2 Uint64Set) bool {
+	if len(set1) != len(set2) {
+		return false
+	}
+	for k := range set1 {
+		if !set2[k] {
+			return false
+		}
+	}
+	return true
+}
+
+// Sorted returns the elements of the set in increasing order
+func (set1 Uint64Set) Sorted() []uint64 {
+	keys := make([]uint64, 0, len(set1))
+	for k := range set1 {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
+
+// String returns a string readable string representation of a set
+func (set1 Uint64Set) String() string {
+	var sb strings.Builder
+	sb.WriteString("Set{")
+	for _, key := range set1.Sorted() {
+		sb.WriteString(fmt.Sprintf("%d,", key))
+	}
+	sb.WriteString(fmt.Sprintf("(%d)}", len(set1)))
+	return sb.String()
+}
+
+// MarshalJSON encodes the set as a sorted JSON array
+func (set1 Uint64Set) MarshalJSON() ([]byte, error) {
+	return json.Marshal(set1.Sorted())
+}
+
+// UnmarshalJSON decodes a JSON array into the set
+func (set1 *Uint64Set) UnmarshalJSON(data []byte) error {
+	var keys []uint64
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return err
+	}
+	*set1 = MakeUint64Set(keys)
+	return nil
+}
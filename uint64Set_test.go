@@ -0,0 +1,75 @@
+package reductions
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestUint64Set_Operations(t *testing.T) {
+	set1 := MakeUint64Set([]uint64{1, 2, 3, 4})
+	set2 := MakeUint64Set([]uint64{3, 4, 5})
+
+	var tests = []struct {
+		name        string
+		ans, wanted Uint64Set
+	}{
+		{name: "Intersection", ans: set1.Intersection(set2), wanted: MakeUint64Set([]uint64{3, 4})},
+		{name: "Union", ans: set1.Union(set2), wanted: MakeUint64Set([]uint64{1, 2, 3, 4, 5})},
+		{name: "Difference", ans: set1.Difference(set2), wanted: MakeUint64Set([]uint64{1, 2})},
+		{name: "SymmetricDifference", ans: set1.SymmetricDifference(set2), wanted: MakeUint64Set([]uint64{1, 2, 5})},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			if !testCase.ans.IsEqual(testCase.wanted) {
+				t.Errorf("%s not equal to %s", testCase.ans.String(), testCase.wanted.String())
+			}
+		})
+	}
+
+	if n := set1.IntersectionLen(set2); n != 2 {
+		t.Errorf("wanted intersection length 2, got %d", n)
+	}
+	if !MakeUint64Set([]uint64{3}).IsSubset(set2) || set1.IsSubset(set2) {
+		t.Errorf("error in subset computation")
+	}
+}
+
+func TestUint64Set_InPlace(t *testing.T) {
+	set := MakeUint64Set([]uint64{1, 2, 3})
+	set.UnionInPlace(MakeUint64Set([]uint64{3, 10}))
+	wanted := MakeUint64Set([]uint64{1, 2, 3, 10})
+	if !set.IsEqual(wanted) {
+		t.Errorf("%s not equal to %s", set.String(), wanted.String())
+	}
+	set.IntersectionInPlace(MakeUint64Set([]uint64{2, 10, 11}))
+	wanted = MakeUint64Set([]uint64{2, 10})
+	if !set.IsEqual(wanted) {
+		t.Errorf("%s not equal to %s", set.String(), wanted.String())
+	}
+}
+
+func TestUint64Set_String(t *testing.T) {
+	set := MakeUint64Set([]uint64{10, 2, 33})
+	wanted := "Set{2,10,33,(3)}"
+	if ans := set.String(); ans != wanted {
+		t.Errorf("Wanted: %v\n got: %v", wanted, ans)
+	}
+}
+
+func TestUint64Set_JSON(t *testing.T) {
+	set := MakeUint64Set([]uint64{10, 2, 33})
+	encoded, err := json.Marshal(set)
+	if err != nil {
+		t.Fatalf("error marshalling set: %v", err)
+	}
+	if string(encoded) != "[2,10,33]" {
+		t.Errorf("unexpected encoding: %s", encoded)
+	}
+	decoded := Uint64Set{}
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatalf("error unmarshalling set: %v", err)
+	}
+	if !decoded.IsEqual(set) {
+		t.Errorf("%s not equal to %s", decoded.String(), set.String())
+	}
+}